@@ -0,0 +1,607 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/YASSER-MN/ultra-siem/go-services/bridge/rules"
+)
+
+// rowResult pairs a batcher's flattened ClickHouse column values with
+// whatever enrichment metadata is worth carrying along if this message
+// ends up dead-lettered, so a replayed message doesn't lose the work
+// enrichment already did on it.
+type rowResult struct {
+	Row               []interface{}
+	EnrichmentHeaders map[string]string
+}
+
+// tableBatcher pulls messages for one ClickHouse table off a durable
+// JetStream pull consumer, accumulates them until BatchSize or
+// BatchTimeout is hit, and only Acks once the batch insert actually
+// succeeds. A message that can't even be parsed (buildRow fails) is
+// poison — it will never succeed no matter how many times it's
+// redelivered — and goes straight to a reason-specific DLQ subject
+// instead of being retried.
+type tableBatcher struct {
+	bridge    *SimpleBridge
+	table     string
+	subject   string
+	insertSQL string
+	buildRow  func(msg *nats.Msg) (*rowResult, error)
+
+	sub pullSubscription
+	wg  sync.WaitGroup
+}
+
+// pullSubscription is the slice of *nats.Subscription's pull-consumer API
+// run and reportConsumerLag actually use, narrowed out so tests can drive
+// run's deadline/flush logic with a fake instead of a real JetStream
+// consumer.
+type pullSubscription interface {
+	Fetch(batch int, opts ...nats.PullOpt) ([]*nats.Msg, error)
+	ConsumerInfo() (*nats.ConsumerInfo, error)
+}
+
+// startTableBatcher creates the durable pull consumer for subject and
+// starts the batcher's run loop in the background.
+func (b *SimpleBridge) startTableBatcher(table, subject, durable, insertSQL string, buildRow func(*nats.Msg) (*rowResult, error)) (*tableBatcher, error) {
+	sub, err := b.js.PullSubscribe(subject, durable, nats.ManualAck(), nats.AckExplicit())
+	if err != nil {
+		return nil, fmt.Errorf("pull subscribe to %s: %w", subject, err)
+	}
+
+	tb := &tableBatcher{
+		bridge:    b,
+		table:     table,
+		subject:   subject,
+		insertSQL: insertSQL,
+		buildRow:  buildRow,
+		sub:       sub,
+	}
+	tb.wg.Add(1)
+	go tb.run()
+	return tb, nil
+}
+
+// run is the batcher's main loop: it pulls messages in BatchSize-sized (or
+// smaller) chunks, flushing whenever BatchSize is reached or BatchTimeout
+// has elapsed since the current batch's first message, whichever comes
+// first. On b.bridge.ctx cancellation it flushes whatever is pending
+// before returning, so Shutdown never drops a partial batch.
+func (tb *tableBatcher) run() {
+	defer tb.wg.Done()
+
+	var pendingMsgs []*nats.Msg
+	var pendingResults []*rowResult
+	deadline := time.Now().Add(tb.bridge.config.BatchTimeout)
+
+	flush := func() {
+		if len(pendingMsgs) == 0 {
+			return
+		}
+		tb.flush(pendingMsgs, pendingResults)
+		pendingMsgs, pendingResults = nil, nil
+		deadline = time.Now().Add(tb.bridge.config.BatchTimeout)
+	}
+
+	for {
+		if tb.bridge.ctx.Err() != nil {
+			flush()
+			return
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			// Always advance the deadline here, even if flush() was a
+			// no-op because pendingMsgs was empty — otherwise an idle
+			// subject sees the same expired deadline forever and spins
+			// without ever reaching sub.Fetch again.
+			flush()
+			deadline = time.Now().Add(tb.bridge.config.BatchTimeout)
+			continue
+		}
+
+		want := tb.bridge.config.BatchSize - len(pendingMsgs)
+		msgs, err := tb.sub.Fetch(want, nats.MaxWait(remaining))
+		if err != nil {
+			if err == nats.ErrTimeout {
+				continue
+			}
+			if tb.bridge.ctx.Err() != nil {
+				flush()
+				return
+			}
+			log.Printf("⚠️ %s pull fetch error: %v", tb.table, err)
+			time.Sleep(time.Second)
+			continue
+		}
+
+		for _, m := range msgs {
+			tb.bridge.metrics.eventsPerSubject.WithLabelValues(tb.subject).Inc()
+			if meta, err := m.Metadata(); err == nil && meta.NumDelivered > 1 {
+				tb.bridge.metrics.natsRedeliveries.WithLabelValues(tb.table).Inc()
+			}
+
+			result, err := tb.buildRow(m)
+			if err != nil {
+				tb.deadLetter(m, err, nil)
+				continue
+			}
+			pendingMsgs = append(pendingMsgs, m)
+			pendingResults = append(pendingResults, result)
+		}
+
+		if len(pendingMsgs) >= tb.bridge.config.BatchSize {
+			flush()
+		}
+	}
+}
+
+// reportConsumerLag polls the pull consumer's NumPending every 10s and
+// updates the per-table consumer-lag gauge, until ctx is cancelled.
+func (tb *tableBatcher) reportConsumerLag(ctx context.Context) {
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			info, err := tb.sub.ConsumerInfo()
+			if err != nil {
+				log.Printf("⚠️ %s consumer info: %v", tb.table, err)
+				continue
+			}
+			tb.bridge.metrics.consumerLag.WithLabelValues(tb.table).Set(float64(info.NumPending))
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// flush inserts rows as a single ClickHouse batch, retrying with
+// exponential backoff and jitter up to MaxRetries. On success every
+// message is Acked; on persistent failure every message in the batch is
+// individually dead-lettered to a subject named after the failure's
+// reason, with the retry count, last error, and any enrichment state
+// preserved as headers for ReplayDLQ to restore later.
+func (tb *tableBatcher) flush(msgs []*nats.Msg, results []*rowResult) {
+	ctx, cancel := context.WithTimeout(context.Background(), tb.bridge.config.QueryTimeout)
+	defer cancel()
+
+	rows := make([][]interface{}, len(results))
+	for i, r := range results {
+		rows[i] = r.Row
+	}
+
+	tb.bridge.metrics.batchSize.WithLabelValues(tb.table).Observe(float64(len(rows)))
+	flushStart := time.Now()
+	err := tb.insertWithRetry(ctx, rows)
+	tb.bridge.metrics.batchFlushDuration.WithLabelValues(tb.table).Observe(time.Since(flushStart).Seconds())
+
+	if err != nil {
+		log.Printf("❌ %s batch insert failed after %d retries, dead-lettering %d message(s): %v", tb.table, tb.bridge.config.MaxRetries, len(msgs), err)
+		for i, m := range msgs {
+			tb.deadLetter(m, err, results[i].EnrichmentHeaders)
+		}
+		tb.bridge.updateErrorStats()
+		tb.bridge.metrics.clickhouseInsertErrors.WithLabelValues(tb.table).Inc()
+		return
+	}
+
+	for _, m := range msgs {
+		if err := m.Ack(); err != nil {
+			log.Printf("⚠️ ack failed for %s: %v", tb.table, err)
+		}
+	}
+
+	if tb.table == "threats" {
+		for range msgs {
+			tb.bridge.updateThreatStats()
+		}
+	} else {
+		for range msgs {
+			tb.bridge.updateEventStats()
+		}
+	}
+}
+
+// insertWithRetry prepares and sends one ClickHouse batch, retrying up to
+// MaxRetries times with exponential backoff (starting at RetryDelay) plus
+// up to 50% jitter, so a fleet of bridges recovering from the same outage
+// doesn't retry ClickHouse in lockstep. PrepareBatch and batch.Send each
+// run inside their own child span, and a successful send records the
+// rows-written count and insert latency as OTel metrics.
+func (tb *tableBatcher) insertWithRetry(ctx context.Context, rows [][]interface{}) error {
+	ctx, span := tb.bridge.tracer.Start(ctx, "insertWithRetry", trace.WithAttributes(
+		attribute.String("table", tb.table),
+		attribute.Int("rows", len(rows)),
+	))
+	defer span.End()
+
+	var lastErr error
+	delay := tb.bridge.config.RetryDelay
+
+	for attempt := 0; attempt <= tb.bridge.config.MaxRetries; attempt++ {
+		if attempt > 0 {
+			jitter := time.Duration(rand.Int63n(int64(delay)/2 + 1))
+			time.Sleep(delay + jitter)
+			delay *= 2
+		}
+
+		prepareStart := time.Now()
+		_, prepareSpan := tb.bridge.tracer.Start(ctx, "PrepareBatch")
+		batch, err := tb.bridge.db.PrepareBatch(ctx, tb.insertSQL)
+		prepareSpan.End()
+		if err != nil {
+			lastErr = fmt.Errorf("preparing batch: %w", err)
+			continue
+		}
+
+		appendFailed := false
+		for _, row := range rows {
+			if err := batch.Append(row...); err != nil {
+				lastErr = fmt.Errorf("appending to batch: %w", err)
+				appendFailed = true
+				break
+			}
+		}
+		if appendFailed {
+			continue
+		}
+
+		_, sendSpan := tb.bridge.tracer.Start(ctx, "batch.Send")
+		err = batch.Send()
+		sendSpan.End()
+		if err != nil {
+			lastErr = fmt.Errorf("sending batch: %w", err)
+			continue
+		}
+
+		latency := time.Since(prepareStart)
+		tb.bridge.chInstruments.rowsWritten.Add(ctx, int64(len(rows)))
+		tb.bridge.chInstruments.insertLatency.Record(ctx, latency.Seconds())
+		return nil
+	}
+
+	span.SetStatus(codes.Error, lastErr.Error())
+	return lastErr
+}
+
+// dlqEnvelope carries a dead-lettered message's original payload, the
+// error that made it un-processable, and enough retry/enrichment context
+// for ReplayDLQ (or an operator reading the DLQ by hand) to decide what to
+// do with it.
+type dlqEnvelope struct {
+	Subject           string            `json:"subject"`
+	Table             string            `json:"table"`
+	Data              json.RawMessage   `json:"data"`
+	Reason            string            `json:"reason"`
+	LastError         string            `json:"last_error"`
+	RetryCount        int               `json:"retry_count"`
+	EnrichmentHeaders map[string]string `json:"enrichment_headers,omitempty"`
+	FailedAt          time.Time         `json:"failed_at"`
+}
+
+// classifyDLQReason turns a buildRow/insertWithRetry error into a short,
+// stable reason string, used both as the last segment of the DLQ subject
+// (<DLQSubjectPrefix>.<reason>) and as the dlqMessages metric's "reason"
+// label, so operators can tell a ClickHouse schema mismatch apart from a
+// transient connection drop without grepping error text.
+func classifyDLQReason(cause error) string {
+	msg := cause.Error()
+	switch {
+	case strings.Contains(msg, "unmarshal"), strings.Contains(msg, "parsing"), strings.Contains(msg, "no parser registered"):
+		return "parse-error"
+	case strings.Contains(msg, "appending to batch"):
+		return "schema-mismatch"
+	case strings.Contains(msg, "preparing batch"), strings.Contains(msg, "sending batch"):
+		return "clickhouse-connection"
+	default:
+		return "unknown"
+	}
+}
+
+// deadLetter publishes m to <DLQSubjectPrefix>.<reason> with its original
+// payload, retry count, and enrichmentHeaders preserved as both the
+// envelope body and NATS headers, then Acks the original so it stops
+// being redelivered.
+func (tb *tableBatcher) deadLetter(m *nats.Msg, cause error, enrichmentHeaders map[string]string) {
+	reason := classifyDLQReason(cause)
+
+	retryCount := 0
+	if meta, err := m.Metadata(); err == nil {
+		retryCount = int(meta.NumDelivered) - 1
+	}
+
+	envelope := dlqEnvelope{
+		Subject:           m.Subject,
+		Table:             tb.table,
+		Data:              json.RawMessage(m.Data),
+		Reason:            reason,
+		LastError:         cause.Error(),
+		RetryCount:        retryCount,
+		EnrichmentHeaders: enrichmentHeaders,
+		FailedAt:          time.Now(),
+	}
+
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		log.Printf("❌ failed to marshal %s DLQ envelope: %v", tb.table, err)
+		m.Nak()
+		return
+	}
+
+	subject := fmt.Sprintf("%s.%s", tb.bridge.config.DLQSubjectPrefix, reason)
+
+	dlqMsg := &nats.Msg{Subject: subject, Data: payload, Header: nats.Header{}}
+	dlqMsg.Header.Set("X-Original-Subject", m.Subject)
+	dlqMsg.Header.Set("X-Table", tb.table)
+	dlqMsg.Header.Set("X-Retry-Count", strconv.Itoa(retryCount))
+	dlqMsg.Header.Set("X-Last-Error", cause.Error())
+	for k, v := range enrichmentHeaders {
+		dlqMsg.Header.Set("X-Enrich-"+k, v)
+	}
+
+	if _, err := tb.bridge.js.PublishMsg(dlqMsg); err != nil {
+		log.Printf("❌ failed to publish to %s: %v", subject, err)
+		m.Nak()
+		return
+	}
+
+	log.Printf("☠️ %s: dead-lettered message to %s: %v", tb.table, subject, cause)
+	m.Ack()
+	tb.bridge.updateErrorStats()
+	tb.bridge.metrics.dlqMessages.WithLabelValues(tb.table, reason).Inc()
+}
+
+// buildThreatsRow unmarshals a ultra_siem.threats message and builds its
+// ultra_siem.threats column arguments, in the same order and with the same
+// placeholder defaults as the old per-message processThreatEvent. A message
+// that fails to unmarshal is poison and returns an error so the caller
+// dead-letters it instead of retrying.
+func (b *SimpleBridge) buildThreatsRow(msg *nats.Msg) (*rowResult, error) {
+	ctx := extractTraceContext(context.Background(), msg)
+	ctx, span := b.tracer.Start(ctx, "buildThreatsRow")
+	defer span.End()
+
+	var event ThreatEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return nil, fmt.Errorf("unmarshaling threat event: %w", err)
+	}
+
+	geoData := b.enrichIPTraced(ctx, event.SourceIP)
+
+	row := []interface{}{
+		generateUUID(),
+		time.Unix(event.Timestamp, 0),
+		event.ThreatType,
+		float32(event.Confidence),
+		event.SourceIP,
+		"", // destination_ip
+		0,  // source_port
+		0,  // destination_port
+		"", // protocol
+		event.Payload,
+		fmt.Sprintf("{\"source_ip\":\"%s\",\"confidence\":%.2f}", event.SourceIP, event.Confidence),
+		uint8(event.Severity),
+		"new",
+		"",  // user
+		"",  // hostname
+		"",  // process
+		0,   // process_id
+		"",  // log_source
+		"",  // raw_message
+		0,   // event_id
+		"",  // session_id
+		"",  // user_agent
+		"",  // request_uri
+		"",  // http_method
+		0,   // response_code
+		0,   // bytes_transferred
+		"",  // command_line
+		"",  // file_hash
+		"",  // registry_key
+		"",  // network_connection
+		"",  // dns_query
+		"",  // certificate_info
+		"",  // threat_intelligence_match
+		0.0, // ml_score
+		0,   // false_positive
+		"",  // analyst_notes
+		"",  // remediation_status
+		"",  // incident_id
+		"",  // compliance_tags
+		"",  // data_classification
+		"",  // retention_policy
+		1,   // encryption_status
+		"",  // audit_trail
+		geoData.Country,
+		geoData.City,
+		geoData.Region,
+		geoData.Latitude,
+		geoData.Longitude,
+		geoData.ASN,
+		geoData.ASName,
+		boolToUint8(geoData.IsTor),
+		geoData.Reputation,
+	}
+
+	headers := map[string]string{"source_ip": event.SourceIP, "threat_type": event.ThreatType}
+	return &rowResult{Row: row, EnrichmentHeaders: headers}, nil
+}
+
+// buildEventsRow unmarshals a ultra_siem.events message and builds its
+// ultra_siem.events column arguments, mirroring the old processUltraSIEMEvent:
+// enrichUltraSIEMEvent first fills in UUID/defaults/compliance tags, then the
+// source IP is enriched again for the geoip_* columns.
+func (b *SimpleBridge) buildEventsRow(msg *nats.Msg) (*rowResult, error) {
+	ctx := extractTraceContext(context.Background(), msg)
+	ctx, span := b.tracer.Start(ctx, "buildEventsRow")
+	defer span.End()
+
+	var event UltraSIEMEvent
+	if err := json.Unmarshal(msg.Data, &event); err != nil {
+		return nil, fmt.Errorf("unmarshaling UltraSIEM event: %w", err)
+	}
+
+	return b.buildEventsRowFromEvent(ctx, &event)
+}
+
+// buildEventsRowFromEvent runs the enrichment/Sigma/column-building steps
+// shared by every ultra_siem.events producer, whether the event arrived as
+// JSON (buildEventsRow) or was decoded from a raw CEF/LEEF/syslog message
+// (buildRawEventRow). The event's enriched Metadata (GeoIP/ASN/MITRE ATT&CK
+// tags) is carried back in the result's EnrichmentHeaders, so a
+// dead-lettered message doesn't lose enrichment that's expensive to redo.
+func (b *SimpleBridge) buildEventsRowFromEvent(ctx context.Context, event *UltraSIEMEvent) (*rowResult, error) {
+	b.enrichUltraSIEMEvent(ctx, event)
+	geoData := b.enrichIPTraced(ctx, event.SourceIP)
+	b.evaluateSigmaRules(event)
+	b.metrics.eventsByTypeAndSource.WithLabelValues(event.EventType, event.LogSource).Inc()
+
+	metadataJSON, _ := json.Marshal(event.Metadata)
+	complianceTagsJSON, _ := json.Marshal(event.ComplianceTags)
+
+	row := []interface{}{
+		generateUUID(),
+		time.Unix(event.Timestamp, 0),
+		event.SourceIP,
+		event.DestinationIP,
+		event.SourcePort,
+		event.DestinationPort,
+		event.Protocol,
+		event.EventType,
+		uint8(event.Severity),
+		event.User,
+		event.Hostname,
+		event.Process,
+		event.ProcessID,
+		event.LogSource,
+		event.Message,
+		event.RawMessage,
+		event.EventID,
+		event.SessionID,
+		event.UserAgent,
+		event.RequestURI,
+		event.HTTPMethod,
+		event.ResponseCode,
+		event.BytesTransferred,
+		event.CommandLine,
+		event.FileHash,
+		event.RegistryKey,
+		event.NetworkConnection,
+		event.DNSQuery,
+		event.CertificateInfo,
+		event.ThreatIntelligenceMatch,
+		event.MLScore,
+		boolToUint8(event.FalsePositive),
+		event.AnalystNotes,
+		event.RemediationStatus,
+		event.IncidentID,
+		string(complianceTagsJSON),
+		event.DataClassification,
+		event.RetentionPolicy,
+		boolToUint8(event.EncryptionStatus),
+		event.AuditTrail,
+		string(metadataJSON),
+		geoData.Country,
+		geoData.City,
+		geoData.Region,
+		geoData.Latitude,
+		geoData.Longitude,
+		geoData.ASN,
+		geoData.ASName,
+		boolToUint8(geoData.IsTor),
+		geoData.Reputation,
+	}
+
+	headers := map[string]string{"event_type": event.EventType, "source_ip": event.SourceIP}
+	if len(event.Metadata) > 0 {
+		headers["metadata"] = string(metadataJSON)
+	}
+	return &rowResult{Row: row, EnrichmentHeaders: headers}, nil
+}
+
+// enrichIPTraced wraps EnrichIP in a child span and records the call's
+// latency on the bridge's enrichment-duration histogram.
+func (b *SimpleBridge) enrichIPTraced(ctx context.Context, ip string) *GeoIPData {
+	_, span := b.tracer.Start(ctx, "EnrichIP")
+	defer span.End()
+
+	b.mu.RLock()
+	enricher := b.enricher
+	b.mu.RUnlock()
+
+	start := time.Now()
+	geoData := enricher.EnrichIP(ip)
+	b.metrics.enrichmentLatency.Observe(time.Since(start).Seconds())
+	return geoData
+}
+
+// evaluateSigmaRules runs the loaded Sigma rules against event, records a
+// hit counter per matching rule, populates IncidentID/ThreatIntelligenceMatch
+// on the event when a rule fires, and publishes a synthesized ThreatEvent to
+// ultra_siem.threats for each match so it flows through the threats batcher
+// like any other detection.
+func (b *SimpleBridge) evaluateSigmaRules(event *UltraSIEMEvent) {
+	matches := b.rulesEngine.Evaluate(rules.FieldsFromStruct(*event))
+	for _, match := range matches {
+		b.stats.recordRuleHit(match.RuleID)
+
+		if event.IncidentID == "" {
+			event.IncidentID = generateUUID()
+		}
+		if event.ThreatIntelligenceMatch == "" {
+			event.ThreatIntelligenceMatch = match.Title
+		} else {
+			event.ThreatIntelligenceMatch += "," + match.Title
+		}
+
+		threat := ThreatEvent{
+			Timestamp:  event.Timestamp,
+			SourceIP:   event.SourceIP,
+			ThreatType: "sigma:" + match.Title,
+			Payload:    event.RawMessage,
+			Severity:   severityForLevel(match.Level),
+			Confidence: 1.0,
+		}
+		data, err := json.Marshal(threat)
+		if err != nil {
+			log.Printf("⚠️ failed to marshal sigma match %q as threat event: %v", match.Title, err)
+			continue
+		}
+		if _, err := b.js.Publish("ultra_siem.threats", data); err != nil {
+			log.Printf("⚠️ failed to publish sigma match %q to ultra_siem.threats: %v", match.Title, err)
+		}
+	}
+}
+
+// severityForLevel maps a Sigma rule's "level" field to the same 1-10
+// severity scale the rest of the bridge uses for ThreatEvent.Severity.
+func severityForLevel(level string) int {
+	switch level {
+	case "critical":
+		return 10
+	case "high":
+		return 8
+	case "medium":
+		return 5
+	case "low":
+		return 2
+	default:
+		return 5
+	}
+}
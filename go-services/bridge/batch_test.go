@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// fakePullSubscription is a pullSubscription that never has anything to
+// deliver: every Fetch times out immediately, simulating an idle subject.
+type fakePullSubscription struct {
+	fetchCalls int64
+}
+
+func (f *fakePullSubscription) Fetch(batch int, opts ...nats.PullOpt) ([]*nats.Msg, error) {
+	atomic.AddInt64(&f.fetchCalls, 1)
+	return nil, nats.ErrTimeout
+}
+
+func (f *fakePullSubscription) ConsumerInfo() (*nats.ConsumerInfo, error) {
+	return nil, errors.New("fakePullSubscription: ConsumerInfo not implemented")
+}
+
+// TestTableBatcherRunKeepsFetchingAnIdleSubject is a regression test for a
+// livelock where, once a batch's deadline expired with nothing pending to
+// flush, run never recomputed a fresh deadline and so looped forever on the
+// "deadline expired" branch without ever calling sub.Fetch again. It
+// asserts Fetch keeps getting called well past the first timeout on an
+// idle subject.
+func TestTableBatcherRunKeepsFetchingAnIdleSubject(t *testing.T) {
+	fake := &fakePullSubscription{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	tb := &tableBatcher{
+		bridge: &SimpleBridge{
+			ctx:    ctx,
+			config: &BridgeConfig{BatchSize: 100, BatchTimeout: 5 * time.Millisecond},
+		},
+		table:   "events",
+		subject: "test.subject",
+		sub:     fake,
+	}
+	tb.wg.Add(1)
+	go tb.run()
+
+	time.Sleep(50 * time.Millisecond)
+	firstCount := atomic.LoadInt64(&fake.fetchCalls)
+	if firstCount == 0 {
+		t.Fatal("expected sub.Fetch to be called at least once")
+	}
+
+	time.Sleep(50 * time.Millisecond)
+	secondCount := atomic.LoadInt64(&fake.fetchCalls)
+	if secondCount <= firstCount {
+		t.Errorf("sub.Fetch stopped being called past the first timeout on an idle subject: had %d calls, still %d 50ms later", firstCount, secondCount)
+	}
+
+	cancel()
+	done := make(chan struct{})
+	go func() {
+		tb.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("tableBatcher.run did not return after context cancellation")
+	}
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/nats-io/nats.go"
+)
+
+// DLQFilter decides whether a dead-lettered envelope should be replayed;
+// a nil filter replays everything ReplayDLQ fetches.
+type DLQFilter func(envelope dlqEnvelope) bool
+
+// ReplayDLQ drains subject (typically one reason subject, e.g.
+// "<DLQSubjectPrefix>.clickhouse-connection", or a wildcard like
+// "<DLQSubjectPrefix>.>" for every reason) back into each message's
+// original subject, so it re-enters the normal batching/retry pipeline.
+// Envelopes filter rejects are left unacked (neither Acked nor Nak'd), so
+// they stay pending and aren't redelivered to this same run's Fetch calls;
+// they become redeliverable again only once AckWait elapses, for a later
+// ReplayDLQ call with a matching filter. Nak'ing them instead would make
+// them immediately redeliverable, so a filter that excludes even one
+// message would have this loop fetch, reject, and Nak the same message
+// forever. It returns the number of messages successfully replayed.
+func (b *SimpleBridge) ReplayDLQ(ctx context.Context, subject string, filter DLQFilter) (int, error) {
+	sub, err := b.js.PullSubscribe(subject, "", nats.ManualAck())
+	if err != nil {
+		return 0, fmt.Errorf("subscribing to DLQ subject %s: %w", subject, err)
+	}
+	defer sub.Unsubscribe()
+
+	replayed := 0
+	for {
+		if ctx.Err() != nil {
+			return replayed, ctx.Err()
+		}
+
+		msgs, err := sub.Fetch(b.config.BatchSize, nats.MaxWait(2*time.Second))
+		if err != nil {
+			if err == nats.ErrTimeout {
+				return replayed, nil
+			}
+			return replayed, fmt.Errorf("fetching DLQ messages from %s: %w", subject, err)
+		}
+		if len(msgs) == 0 {
+			return replayed, nil
+		}
+
+		for _, m := range msgs {
+			var envelope dlqEnvelope
+			if err := json.Unmarshal(m.Data, &envelope); err != nil {
+				log.Printf("⚠️ skipping unparsable DLQ message on %s: %v", m.Subject, err)
+				m.Term()
+				continue
+			}
+
+			if filter != nil && !filter(envelope) {
+				// Leave unacked rather than Nak: Nak would make this exact
+				// message immediately redeliverable and this loop would
+				// just fetch, reject, and Nak it again forever.
+				continue
+			}
+
+			if _, err := b.js.Publish(envelope.Subject, envelope.Data); err != nil {
+				log.Printf("⚠️ replaying DLQ message to %s failed: %v", envelope.Subject, err)
+				m.Nak()
+				continue
+			}
+
+			m.Ack()
+			replayed++
+		}
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"container/list"
+	"net"
+	"sync"
+	"time"
+)
+
+// Enricher produces partial GeoIP/threat-intel data for a single IP. Each
+// provider contributes what it knows and nothing else: a MaxMind reader
+// fills in Country/City/ASN, a Tor-exit list only ever sets IsTor, a
+// threat-intel feed only sets ThreatIntelligenceMatch/Reputation. Enrich
+// returning an error (feed unreachable, no mmdb loaded) just means that
+// provider contributes nothing for this lookup; it does not fail the
+// overall enrichment.
+type Enricher interface {
+	Name() string
+	Enrich(ip net.IP) (EnrichmentResult, error)
+}
+
+// EnrichmentResult is one provider's contribution to a GeoIPData. Zero
+// values are treated as "provider has no opinion" by mergeEnrichment, so a
+// threat-intel feed can leave Country/City empty without clobbering what an
+// earlier provider already set.
+type EnrichmentResult struct {
+	Country                 string
+	City                    string
+	Region                  string
+	Latitude                float64
+	Longitude               float64
+	ASN                     uint32
+	ASName                  string
+	IsTor                   bool
+	Reputation              float32
+	ThreatIntelligenceMatch string
+}
+
+// mergeEnrichment folds src into dst, field by field, only overwriting a
+// dst field when src actually set one. IsTor is sticky: once any provider
+// reports Tor, later providers can't un-set it.
+func mergeEnrichment(dst *GeoIPData, src EnrichmentResult) {
+	if src.Country != "" {
+		dst.Country = src.Country
+	}
+	if src.City != "" {
+		dst.City = src.City
+	}
+	if src.Region != "" {
+		dst.Region = src.Region
+	}
+	if src.Latitude != 0 {
+		dst.Latitude = src.Latitude
+	}
+	if src.Longitude != 0 {
+		dst.Longitude = src.Longitude
+	}
+	if src.ASN != 0 {
+		dst.ASN = src.ASN
+	}
+	if src.ASName != "" {
+		dst.ASName = src.ASName
+	}
+	if src.IsTor {
+		dst.IsTor = true
+	}
+	if src.Reputation != 0 {
+		dst.Reputation = src.Reputation
+	}
+	if src.ThreatIntelligenceMatch != "" {
+		if dst.ThreatIntelligenceMatch == "" {
+			dst.ThreatIntelligenceMatch = src.ThreatIntelligenceMatch
+		} else {
+			dst.ThreatIntelligenceMatch += "," + src.ThreatIntelligenceMatch
+		}
+	}
+}
+
+// enrichCacheEntry is a cached lookup result. data == nil marks a negative
+// cache entry (the providers were consulted and found nothing for this IP),
+// which still has its own, shorter TTL below.
+type enrichCacheEntry struct {
+	key       string
+	data      *GeoIPData
+	expiresAt time.Time
+}
+
+// enrichCache is a bounded LRU with per-entry TTL backing EnrichmentPipeline.
+// It replaces the old unbounded map[string]*GeoIPData: an internet-facing
+// SIEM sees an unbounded number of distinct source IPs, so a cache without
+// an eviction policy is a slow memory leak, and without negative caching a
+// flood of bogus/unroutable IPs would hit every provider on every event.
+type enrichCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	negTTL   time.Duration
+	ll       *list.List
+	items    map[string]*list.Element
+}
+
+func newEnrichCache(capacity int, ttl, negTTL time.Duration) *enrichCache {
+	return &enrichCache{
+		capacity: capacity,
+		ttl:      ttl,
+		negTTL:   negTTL,
+		ll:       list.New(),
+		items:    make(map[string]*list.Element, capacity),
+	}
+}
+
+// get returns (data, found). A found negative entry returns (nil, true) so
+// callers can distinguish "known miss, don't re-query providers" from
+// "never looked up".
+func (c *enrichCache) get(key string) (*GeoIPData, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.items[key]
+	if !ok {
+		return nil, false
+	}
+	entry := el.Value.(*enrichCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.ll.Remove(el)
+		delete(c.items, key)
+		return nil, false
+	}
+	c.ll.MoveToFront(el)
+	return entry.data, true
+}
+
+// put inserts or refreshes key. data == nil stores a negative entry with
+// the shorter negTTL; otherwise the entry lives for ttl.
+func (c *enrichCache) put(key string, data *GeoIPData) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	ttl := c.ttl
+	if data == nil {
+		ttl = c.negTTL
+	}
+	entry := &enrichCacheEntry{key: key, data: data, expiresAt: time.Now().Add(ttl)}
+
+	if el, ok := c.items[key]; ok {
+		el.Value = entry
+		c.ll.MoveToFront(el)
+		return
+	}
+
+	el := c.ll.PushFront(entry)
+	c.items[key] = el
+
+	for c.ll.Len() > c.capacity {
+		oldest := c.ll.Back()
+		if oldest == nil {
+			break
+		}
+		c.ll.Remove(oldest)
+		delete(c.items, oldest.Value.(*enrichCacheEntry).key)
+	}
+}
+
+// EnrichmentPipeline replaces the old GeoIPEnricher mock: it runs every
+// configured provider for an IP, merges their results into one GeoIPData,
+// and caches the outcome (positive or negative) so repeat lookups for the
+// same source IP don't re-hit every provider.
+type EnrichmentPipeline struct {
+	providers []Enricher
+	cache     *enrichCache
+	stats     *SimpleStats
+}
+
+// NewEnrichmentPipeline builds a pipeline over providers, backed by an LRU
+// cache of the given size with separate positive/negative TTLs.
+func NewEnrichmentPipeline(providers []Enricher, cacheSize int, ttl, negTTL time.Duration, stats *SimpleStats) *EnrichmentPipeline {
+	return &EnrichmentPipeline{
+		providers: providers,
+		cache:     newEnrichCache(cacheSize, ttl, negTTL),
+		stats:     stats,
+	}
+}
+
+// EnrichIP looks up ip, preferring the cache, and otherwise running every
+// provider and merging their results. It never returns nil.
+func (p *EnrichmentPipeline) EnrichIP(ip string) *GeoIPData {
+	if ip == "" || ip == "0.0.0.0" || ip == "::1" {
+		return &GeoIPData{}
+	}
+
+	if data, found := p.cache.get(ip); found {
+		p.stats.recordCacheHit()
+		if data == nil {
+			return &GeoIPData{}
+		}
+		return data
+	}
+	p.stats.recordCacheMiss()
+
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		p.cache.put(ip, nil)
+		return &GeoIPData{}
+	}
+
+	if parsed.IsPrivate() || parsed.IsLoopback() {
+		data := &GeoIPData{Country: "PRIVATE", City: "Internal", Region: "Internal", Reputation: 100.0}
+		p.cache.put(ip, data)
+		return data
+	}
+
+	data := &GeoIPData{}
+	any := false
+	for _, provider := range p.providers {
+		start := time.Now()
+		result, err := provider.Enrich(parsed)
+		latency := time.Since(start)
+		if err != nil {
+			p.stats.recordProviderMiss(provider.Name(), latency)
+			continue
+		}
+		p.stats.recordProviderHit(provider.Name(), latency)
+		mergeEnrichment(data, result)
+		any = true
+	}
+
+	if !any {
+		p.cache.put(ip, nil)
+		return &GeoIPData{}
+	}
+
+	p.cache.put(ip, data)
+	return data
+}
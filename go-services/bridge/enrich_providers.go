@@ -0,0 +1,376 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	maxminddb "github.com/oschwald/maxminddb-golang"
+)
+
+// MaxMindProvider resolves Country/City/ASN from local GeoLite2/GeoIP2
+// .mmdb files. City and ASN live in separate MaxMind databases, so either
+// path may be left empty to disable that half of the lookup.
+type MaxMindProvider struct {
+	cityPath string
+	asnPath  string
+
+	mu      sync.RWMutex
+	cityDB  *maxminddb.Reader
+	asnDB   *maxminddb.Reader
+	cityMod time.Time
+	asnMod  time.Time
+}
+
+// maxMindCityRecord mirrors the subset of GeoLite2-City's schema this
+// provider cares about.
+type maxMindCityRecord struct {
+	Country struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"country"`
+	City struct {
+		Names map[string]string `maxminddb:"names"`
+	} `maxminddb:"city"`
+	Subdivisions []struct {
+		ISOCode string `maxminddb:"iso_code"`
+	} `maxminddb:"subdivisions"`
+	Location struct {
+		Latitude  float64 `maxminddb:"latitude"`
+		Longitude float64 `maxminddb:"longitude"`
+	} `maxminddb:"location"`
+}
+
+// maxMindASNRecord mirrors GeoLite2-ASN's schema.
+type maxMindASNRecord struct {
+	AutonomousSystemNumber       uint32 `maxminddb:"autonomous_system_number"`
+	AutonomousSystemOrganization string `maxminddb:"autonomous_system_organization"`
+}
+
+// NewMaxMindProvider opens the configured .mmdb files and starts a
+// background reloader that re-opens either file when its mtime changes, so
+// a cron-refreshed GeoLite2 database is picked up without a bridge
+// restart. Either path may be empty; the provider just contributes nothing
+// for that half of the lookup.
+func NewMaxMindProvider(cityPath, asnPath string, reloadInterval time.Duration) (*MaxMindProvider, error) {
+	p := &MaxMindProvider{cityPath: cityPath, asnPath: asnPath}
+	if err := p.reload(); err != nil {
+		return nil, err
+	}
+	if reloadInterval > 0 {
+		go p.watchReload(reloadInterval)
+	}
+	return p, nil
+}
+
+func (p *MaxMindProvider) reload() error {
+	if p.cityPath != "" {
+		if info, err := os.Stat(p.cityPath); err == nil && info.ModTime().After(p.cityMod) {
+			db, err := maxminddb.Open(p.cityPath)
+			if err != nil {
+				return fmt.Errorf("opening GeoIP city db: %w", err)
+			}
+			p.mu.Lock()
+			old := p.cityDB
+			p.cityDB = db
+			p.cityMod = info.ModTime()
+			p.mu.Unlock()
+			if old != nil {
+				old.Close()
+			}
+		}
+	}
+
+	if p.asnPath != "" {
+		if info, err := os.Stat(p.asnPath); err == nil && info.ModTime().After(p.asnMod) {
+			db, err := maxminddb.Open(p.asnPath)
+			if err != nil {
+				return fmt.Errorf("opening GeoIP ASN db: %w", err)
+			}
+			p.mu.Lock()
+			old := p.asnDB
+			p.asnDB = db
+			p.asnMod = info.ModTime()
+			p.mu.Unlock()
+			if old != nil {
+				old.Close()
+			}
+		}
+	}
+
+	return nil
+}
+
+func (p *MaxMindProvider) watchReload(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := p.reload(); err != nil {
+			log.Printf("⚠️ MaxMind reload failed: %v", err)
+		}
+	}
+}
+
+func (p *MaxMindProvider) Name() string { return "maxmind" }
+
+func (p *MaxMindProvider) Enrich(ip net.IP) (EnrichmentResult, error) {
+	p.mu.RLock()
+	cityDB, asnDB := p.cityDB, p.asnDB
+	p.mu.RUnlock()
+
+	if cityDB == nil && asnDB == nil {
+		return EnrichmentResult{}, fmt.Errorf("maxmind: no database loaded")
+	}
+
+	var result EnrichmentResult
+
+	if cityDB != nil {
+		var rec maxMindCityRecord
+		if err := cityDB.Lookup(ip, &rec); err == nil {
+			result.Country = rec.Country.ISOCode
+			result.City = rec.City.Names["en"]
+			if len(rec.Subdivisions) > 0 {
+				result.Region = rec.Subdivisions[0].ISOCode
+			}
+			result.Latitude = rec.Location.Latitude
+			result.Longitude = rec.Location.Longitude
+		}
+	}
+
+	if asnDB != nil {
+		var rec maxMindASNRecord
+		if err := asnDB.Lookup(ip, &rec); err == nil {
+			result.ASN = rec.AutonomousSystemNumber
+			result.ASName = rec.AutonomousSystemOrganization
+		}
+	}
+
+	return result, nil
+}
+
+// CIDRListProvider backs both the Tor exit-node list and the Spamhaus DROP
+// list: both are plain-text lists of IPs/CIDR blocks, published at a URL,
+// refreshed on an interval, and checked by simple membership test. label is
+// what gets written into ThreatIntelligenceMatch on a hit.
+type CIDRListProvider struct {
+	name              string
+	url               string
+	label             string
+	reputationPenalty float32
+	setsTor           bool
+
+	mu   sync.RWMutex
+	nets []*net.IPNet
+}
+
+// NewCIDRListProvider fetches url once synchronously (so the bridge doesn't
+// start with an empty list) and then refreshes it in the background every
+// refreshInterval.
+func NewCIDRListProvider(name, url, label string, reputationPenalty float32, setsTor bool, refreshInterval time.Duration) (*CIDRListProvider, error) {
+	p := &CIDRListProvider{name: name, url: url, label: label, reputationPenalty: reputationPenalty, setsTor: setsTor}
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+	if refreshInterval > 0 {
+		go p.watchRefresh(refreshInterval)
+	}
+	return p, nil
+}
+
+func (p *CIDRListProvider) refresh() error {
+	resp, err := http.Get(p.url)
+	if err != nil {
+		return fmt.Errorf("%s: fetching list: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("%s: unexpected status %d", p.name, resp.StatusCode)
+	}
+
+	nets, err := parseCIDRList(resp.Body)
+	if err != nil {
+		return fmt.Errorf("%s: parsing list: %w", p.name, err)
+	}
+
+	p.mu.Lock()
+	p.nets = nets
+	p.mu.Unlock()
+	return nil
+}
+
+// parseCIDRList reads one entry per line, ignoring blank lines and ";"/"#"
+// comments (Spamhaus DROP uses ";", Tor's exit list is bare IPs). A bare IP
+// is treated as a /32 (or /128) host route.
+func parseCIDRList(r io.Reader) ([]*net.IPNet, error) {
+	var nets []*net.IPNet
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, ";") || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if idx := strings.IndexAny(line, " ;"); idx != -1 {
+			line = line[:idx]
+		}
+		if !strings.Contains(line, "/") {
+			ip := net.ParseIP(line)
+			if ip == nil {
+				continue
+			}
+			bits := 32
+			if ip.To4() == nil {
+				bits = 128
+			}
+			line = fmt.Sprintf("%s/%d", line, bits)
+		}
+		_, ipNet, err := net.ParseCIDR(line)
+		if err != nil {
+			continue
+		}
+		nets = append(nets, ipNet)
+	}
+	return nets, scanner.Err()
+}
+
+func (p *CIDRListProvider) watchRefresh(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := p.refresh(); err != nil {
+			log.Printf("⚠️ %s refresh failed: %v", p.name, err)
+		}
+	}
+}
+
+func (p *CIDRListProvider) Name() string { return p.name }
+
+func (p *CIDRListProvider) Enrich(ip net.IP) (EnrichmentResult, error) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	for _, ipNet := range p.nets {
+		if ipNet.Contains(ip) {
+			return EnrichmentResult{
+				IsTor:                   p.setsTor,
+				Reputation:              p.reputationPenalty,
+				ThreatIntelligenceMatch: p.label,
+			}, nil
+		}
+	}
+	return EnrichmentResult{}, nil
+}
+
+// HTTPThreatIntelProvider queries a per-IP REST reputation feed such as
+// AbuseIPDB or AlienVault OTX. urlFormat must contain exactly one %s for the
+// IP address; parse extracts a match label and reputation score from the
+// raw response body.
+type HTTPThreatIntelProvider struct {
+	name      string
+	urlFormat string
+	apiKeyHdr string
+	apiKey    string
+	client    *http.Client
+	parse     func(body []byte) (label string, reputation float32, err error)
+}
+
+// NewHTTPThreatIntelProvider builds a provider for a single-IP-lookup feed.
+// apiKeyHdr is the HTTP header the API key is sent under (feeds disagree:
+// AbuseIPDB uses "Key", OTX uses "X-OTX-API-KEY").
+func NewHTTPThreatIntelProvider(name, urlFormat, apiKeyHdr, apiKey string, parse func([]byte) (string, float32, error)) *HTTPThreatIntelProvider {
+	return &HTTPThreatIntelProvider{
+		name:      name,
+		urlFormat: urlFormat,
+		apiKeyHdr: apiKeyHdr,
+		apiKey:    apiKey,
+		client:    &http.Client{Timeout: 3 * time.Second},
+		parse:     parse,
+	}
+}
+
+func (p *HTTPThreatIntelProvider) Name() string { return p.name }
+
+func (p *HTTPThreatIntelProvider) Enrich(ip net.IP) (EnrichmentResult, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf(p.urlFormat, ip.String()), nil)
+	if err != nil {
+		return EnrichmentResult{}, fmt.Errorf("%s: building request: %w", p.name, err)
+	}
+	req.Header.Set(p.apiKeyHdr, p.apiKey)
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return EnrichmentResult{}, fmt.Errorf("%s: request failed: %w", p.name, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return EnrichmentResult{}, fmt.Errorf("%s: unexpected status %d", p.name, resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return EnrichmentResult{}, fmt.Errorf("%s: reading response: %w", p.name, err)
+	}
+
+	label, reputation, err := p.parse(body)
+	if err != nil {
+		return EnrichmentResult{}, fmt.Errorf("%s: parsing response: %w", p.name, err)
+	}
+
+	return EnrichmentResult{ThreatIntelligenceMatch: label, Reputation: reputation}, nil
+}
+
+// abuseIPDBResponse is the subset of AbuseIPDB's "check" endpoint this
+// bridge cares about.
+type abuseIPDBResponse struct {
+	Data struct {
+		AbuseConfidenceScore int `json:"abuseConfidenceScore"`
+	} `json:"data"`
+}
+
+// parseAbuseIPDB turns an AbuseIPDB confidence score (0-100, higher is
+// worse) into this bridge's reputation scale (0-100, higher is better).
+func parseAbuseIPDB(body []byte) (string, float32, error) {
+	var resp abuseIPDBResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", 0, err
+	}
+	reputation := float32(100 - resp.Data.AbuseConfidenceScore)
+	if resp.Data.AbuseConfidenceScore == 0 {
+		return "", reputation, nil
+	}
+	return fmt.Sprintf("abuseipdb:%d", resp.Data.AbuseConfidenceScore), reputation, nil
+}
+
+// otxResponse is the subset of AlienVault OTX's IP indicator "general"
+// endpoint this bridge cares about.
+type otxResponse struct {
+	PulseInfo struct {
+		Count int `json:"count"`
+	} `json:"pulse_info"`
+}
+
+// parseOTX treats "appears in N OTX pulses" as a reputation penalty scaled
+// by pulse count, capped at a 0 floor.
+func parseOTX(body []byte) (string, float32, error) {
+	var resp otxResponse
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return "", 0, err
+	}
+	if resp.PulseInfo.Count == 0 {
+		return "", 100, nil
+	}
+	reputation := float32(100 - resp.PulseInfo.Count*10)
+	if reputation < 0 {
+		reputation = 0
+	}
+	return fmt.Sprintf("otx:%d-pulses", resp.PulseInfo.Count), reputation, nil
+}
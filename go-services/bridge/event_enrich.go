@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"log"
+	"regexp"
+	"time"
+)
+
+// EventEnricher runs one enrichment step against an already-decoded event.
+// Unlike Enricher (which only ever contributes GeoIP/threat-intel fields for
+// a single IP), an EventEnricher can inspect and mutate any field on the
+// event — MITRE ATT&CK tagging and compliance tagging have nothing to do
+// with IP lookups at all.
+type EventEnricher interface {
+	Name() string
+	Enrich(ctx context.Context, event *UltraSIEMEvent) error
+}
+
+// EventEnrichmentChain runs every registered EventEnricher over an event in
+// order, replacing enrichUltraSIEMEvent's old inline switch. Each enricher
+// gets its own timeout and its own error handling: a slow threat-intel feed
+// or a bug in one enricher only means that enricher's contribution is
+// missing, not a dropped event.
+type EventEnrichmentChain struct {
+	enrichers []EventEnricher
+	timeout   time.Duration
+	metrics   *bridgeMetrics
+}
+
+// NewEventEnrichmentChain builds a chain over enrichers, applying timeout to
+// each Enrich call individually and recording its outcome on metrics.
+func NewEventEnrichmentChain(enrichers []EventEnricher, timeout time.Duration, metrics *bridgeMetrics) *EventEnrichmentChain {
+	return &EventEnrichmentChain{enrichers: enrichers, timeout: timeout, metrics: metrics}
+}
+
+// Run executes every enricher in the chain against event, in registration
+// order, isolating each one's failure from the rest.
+func (c *EventEnrichmentChain) Run(ctx context.Context, event *UltraSIEMEvent) {
+	for _, enricher := range c.enrichers {
+		c.runOne(ctx, enricher, event)
+	}
+}
+
+func (c *EventEnrichmentChain) runOne(ctx context.Context, enricher EventEnricher, event *UltraSIEMEvent) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := enricher.Enrich(ctx, event)
+	c.metrics.eventEnricherDuration.WithLabelValues(enricher.Name()).Observe(time.Since(start).Seconds())
+	if err != nil {
+		log.Printf("⚠️ enricher %s failed: %v", enricher.Name(), err)
+		c.metrics.eventEnricherErrors.WithLabelValues(enricher.Name()).Inc()
+	}
+}
+
+// geoIPEventEnricher looks up event.SourceIP through the bridge's per-IP
+// EnrichmentPipeline and stashes the merged GeoIP/threat-intel result in the
+// event's metadata. The pipeline's own cache means this costs nothing extra
+// over the flat geoip_* columns buildEventsRowFromEvent already computes.
+type geoIPEventEnricher struct {
+	pipeline *EnrichmentPipeline
+}
+
+func (e *geoIPEventEnricher) Name() string { return "geoip" }
+
+func (e *geoIPEventEnricher) Enrich(ctx context.Context, event *UltraSIEMEvent) error {
+	if event.SourceIP == "" {
+		return nil
+	}
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]interface{})
+	}
+	event.Metadata["geoip"] = e.pipeline.EnrichIP(event.SourceIP)
+	return nil
+}
+
+// asnEventEnricher surfaces the ASN/ASName the geoip enricher already looked
+// up as top-level metadata fields, so filtering by autonomous system doesn't
+// require digging into the nested geoip blob.
+type asnEventEnricher struct {
+	pipeline *EnrichmentPipeline
+}
+
+func (e *asnEventEnricher) Name() string { return "asn" }
+
+func (e *asnEventEnricher) Enrich(ctx context.Context, event *UltraSIEMEvent) error {
+	if event.SourceIP == "" {
+		return nil
+	}
+	geoData := e.pipeline.EnrichIP(event.SourceIP)
+	if geoData.ASN == 0 {
+		return nil
+	}
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]interface{})
+	}
+	event.Metadata["asn"] = geoData.ASN
+	event.Metadata["as_name"] = geoData.ASName
+	return nil
+}
+
+// mitreTechnique pairs a MITRE ATT&CK technique ID with the event field and
+// pattern that suggests it.
+type mitreTechnique struct {
+	id      string
+	field   func(*UltraSIEMEvent) string
+	pattern *regexp.Regexp
+}
+
+// mitreTechniques is intentionally coarse string/regex matching for triage
+// tagging, not a replacement for the Sigma rules engine.
+var mitreTechniques = []mitreTechnique{
+	{"T1059", func(e *UltraSIEMEvent) string { return e.CommandLine }, regexp.MustCompile(`(?i)powershell|cmd\.exe|/bin/(ba)?sh`)},
+	{"T1055", func(e *UltraSIEMEvent) string { return e.CommandLine }, regexp.MustCompile(`(?i)rundll32|process\s*hollow|reflective`)},
+	{"T1071", func(e *UltraSIEMEvent) string { return e.NetworkConnection }, regexp.MustCompile(`(?i)https?://|:443\b|:8080\b`)},
+	{"T1112", func(e *UltraSIEMEvent) string { return e.RegistryKey }, regexp.MustCompile(`(?i)\\run\\|\\runonce\\`)},
+	{"T1053", func(e *UltraSIEMEvent) string { return e.CommandLine }, regexp.MustCompile(`(?i)schtasks|crontab|at\.exe`)},
+}
+
+// mitreAttackEnricher tags an event with the MITRE ATT&CK technique IDs
+// suggested by its CommandLine/NetworkConnection/RegistryKey fields.
+type mitreAttackEnricher struct{}
+
+func (e *mitreAttackEnricher) Name() string { return "mitre-attack" }
+
+func (e *mitreAttackEnricher) Enrich(ctx context.Context, event *UltraSIEMEvent) error {
+	var techniques []string
+	for _, t := range mitreTechniques {
+		if value := t.field(event); value != "" && t.pattern.MatchString(value) {
+			techniques = append(techniques, t.id)
+		}
+	}
+	if len(techniques) == 0 {
+		return nil
+	}
+	if event.Metadata == nil {
+		event.Metadata = make(map[string]interface{})
+	}
+	event.Metadata["mitre_attack_techniques"] = techniques
+	return nil
+}
+
+// complianceTagEnricher tags an event with the regulatory frameworks its
+// EventType falls under. This replaces the inline switch
+// enrichUltraSIEMEvent used to run directly.
+type complianceTagEnricher struct{}
+
+func (e *complianceTagEnricher) Name() string { return "compliance-tags" }
+
+func (e *complianceTagEnricher) Enrich(ctx context.Context, event *UltraSIEMEvent) error {
+	switch event.EventType {
+	case "authentication", "login", "logout":
+		event.ComplianceTags = append(event.ComplianceTags, "SOX", "PCI-DSS", "GDPR")
+	case "file_access", "data_access":
+		event.ComplianceTags = append(event.ComplianceTags, "SOX", "HIPAA", "GDPR")
+	case "network_connection", "firewall":
+		event.ComplianceTags = append(event.ComplianceTags, "PCI-DSS", "NIST")
+	case "threat_detection", "malware":
+		event.ComplianceTags = append(event.ComplianceTags, "NIST", "ISO27001")
+	}
+	return nil
+}
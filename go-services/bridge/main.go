@@ -7,10 +7,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
-	"net"
 	"os"
-	"strconv"
-	"strings"
 	"sync"
 	"time"
 
@@ -18,16 +15,20 @@ import (
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/google/uuid"
 	"github.com/nats-io/nats.go"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/YASSER-MN/ultra-siem/go-services/bridge/migrations"
+	"github.com/YASSER-MN/ultra-siem/go-services/bridge/rules"
 )
 
 // ThreatEvent represents the threat event format from Rust core
 type ThreatEvent struct {
-	Timestamp   int64   `json:"timestamp"`
-	SourceIP    string  `json:"source_ip"`
-	ThreatType  string  `json:"threat_type"`
-	Payload     string  `json:"payload"`
-	Severity    int     `json:"severity"`
-	Confidence  float64 `json:"confidence"`
+	Timestamp  int64   `json:"timestamp"`
+	SourceIP   string  `json:"source_ip"`
+	ThreatType string  `json:"threat_type"`
+	Payload    string  `json:"payload"`
+	Severity   int     `json:"severity"`
+	Confidence float64 `json:"confidence"`
 }
 
 // SystemEvent represents system events from Rust core
@@ -41,73 +42,83 @@ type SystemEvent struct {
 
 // Enhanced UltraSIEMEvent struct for all collectors with comprehensive fields
 type UltraSIEMEvent struct {
-	ID             string                 `json:"id"`
-	Timestamp      int64                  `json:"timestamp"`
-	SourceIP       string                 `json:"source_ip"`
-	DestinationIP  string                 `json:"destination_ip"`
-	SourcePort     uint16                 `json:"source_port"`
-	DestinationPort uint16                `json:"destination_port"`
-	Protocol       string                 `json:"protocol"`
-	EventType      string                 `json:"event_type"`
-	Severity       int                    `json:"severity"`
-	User           string                 `json:"user"`
-	Hostname       string                 `json:"hostname"`
-	Process        string                 `json:"process"`
-	ProcessID      uint32                 `json:"process_id"`
-	LogSource      string                 `json:"log_source"`
-	Message        string                 `json:"message"`
-	RawMessage     string                 `json:"raw_message"`
-	EventID        uint32                 `json:"event_id"`
-	SessionID      string                 `json:"session_id"`
-	UserAgent      string                 `json:"user_agent"`
-	RequestURI     string                 `json:"request_uri"`
-	HTTPMethod     string                 `json:"http_method"`
-	ResponseCode   uint16                 `json:"response_code"`
-	BytesTransferred uint64               `json:"bytes_transferred"`
-	CommandLine    string                 `json:"command_line"`
-	FileHash       string                 `json:"file_hash"`
-	RegistryKey    string                 `json:"registry_key"`
-	NetworkConnection string              `json:"network_connection"`
-	DNSQuery       string                 `json:"dns_query"`
-	CertificateInfo string                `json:"certificate_info"`
-	ThreatIntelligenceMatch string        `json:"threat_intelligence_match"`
-	MLScore        float32                `json:"ml_score"`
-	FalsePositive  bool                   `json:"false_positive"`
-	AnalystNotes   string                 `json:"analyst_notes"`
-	RemediationStatus string              `json:"remediation_status"`
-	IncidentID     string                 `json:"incident_id"`
-	ComplianceTags []string               `json:"compliance_tags"`
-	DataClassification string             `json:"data_classification"`
-	RetentionPolicy string                `json:"retention_policy"`
-	EncryptionStatus bool                 `json:"encryption_status"`
-	AuditTrail     string                 `json:"audit_trail"`
-	Metadata       map[string]interface{} `json:"metadata"`
+	ID                      string                 `json:"id"`
+	Timestamp               int64                  `json:"timestamp"`
+	SourceIP                string                 `json:"source_ip"`
+	DestinationIP           string                 `json:"destination_ip"`
+	SourcePort              uint16                 `json:"source_port"`
+	DestinationPort         uint16                 `json:"destination_port"`
+	Protocol                string                 `json:"protocol"`
+	EventType               string                 `json:"event_type"`
+	Severity                int                    `json:"severity"`
+	User                    string                 `json:"user"`
+	Hostname                string                 `json:"hostname"`
+	Process                 string                 `json:"process"`
+	ProcessID               uint32                 `json:"process_id"`
+	LogSource               string                 `json:"log_source"`
+	Message                 string                 `json:"message"`
+	RawMessage              string                 `json:"raw_message"`
+	EventID                 uint32                 `json:"event_id"`
+	SessionID               string                 `json:"session_id"`
+	UserAgent               string                 `json:"user_agent"`
+	RequestURI              string                 `json:"request_uri"`
+	HTTPMethod              string                 `json:"http_method"`
+	ResponseCode            uint16                 `json:"response_code"`
+	BytesTransferred        uint64                 `json:"bytes_transferred"`
+	CommandLine             string                 `json:"command_line"`
+	FileHash                string                 `json:"file_hash"`
+	RegistryKey             string                 `json:"registry_key"`
+	NetworkConnection       string                 `json:"network_connection"`
+	DNSQuery                string                 `json:"dns_query"`
+	CertificateInfo         string                 `json:"certificate_info"`
+	ThreatIntelligenceMatch string                 `json:"threat_intelligence_match"`
+	MLScore                 float32                `json:"ml_score"`
+	FalsePositive           bool                   `json:"false_positive"`
+	AnalystNotes            string                 `json:"analyst_notes"`
+	RemediationStatus       string                 `json:"remediation_status"`
+	IncidentID              string                 `json:"incident_id"`
+	ComplianceTags          []string               `json:"compliance_tags"`
+	DataClassification      string                 `json:"data_classification"`
+	RetentionPolicy         string                 `json:"retention_policy"`
+	EncryptionStatus        bool                   `json:"encryption_status"`
+	AuditTrail              string                 `json:"audit_trail"`
+	Metadata                map[string]interface{} `json:"metadata"`
 }
 
 // GeoIP enrichment data
 type GeoIPData struct {
-	Country     string  `json:"country"`
-	City        string  `json:"city"`
-	Region      string  `json:"region"`
-	Latitude    float64 `json:"latitude"`
-	Longitude   float64 `json:"longitude"`
-	ASN         uint32  `json:"asn"`
-	ASName      string  `json:"as_name"`
-	IsTor       bool    `json:"is_tor"`
-	Reputation  float32 `json:"reputation"`
+	Country                 string  `json:"country"`
+	City                    string  `json:"city"`
+	Region                  string  `json:"region"`
+	Latitude                float64 `json:"latitude"`
+	Longitude               float64 `json:"longitude"`
+	ASN                     uint32  `json:"asn"`
+	ASName                  string  `json:"as_name"`
+	IsTor                   bool    `json:"is_tor"`
+	Reputation              float32 `json:"reputation"`
+	ThreatIntelligenceMatch string  `json:"threat_intelligence_match,omitempty"`
 }
 
 // SimpleBridge handles NATS to ClickHouse bridging with enhanced capabilities
 type SimpleBridge struct {
-	nc     *nats.Conn
-	js     nats.JetStreamContext
-	db     driver.Conn
-	ctx    context.Context
-	cancel context.CancelFunc
-	stats  *SimpleStats
-	mu     sync.RWMutex
-	geoIP  *GeoIPEnricher
-	config *BridgeConfig
+	nc             *nats.Conn
+	js             nats.JetStreamContext
+	db             driver.Conn
+	ctx            context.Context
+	cancel         context.CancelFunc
+	stats          *SimpleStats
+	mu             sync.RWMutex
+	enricher       *EnrichmentPipeline
+	eventEnrichers *EventEnrichmentChain
+	config         *BridgeConfig
+	batchers       []*tableBatcher
+	rulesEngine    *rules.Engine
+
+	tracer         trace.Tracer
+	metrics        *bridgeMetrics
+	chInstruments  *clickhouseInstruments
+	tracerShutdown otelShutdownFunc
+	meterShutdown  otelShutdownFunc
 }
 
 // BridgeConfig holds configuration for the bridge
@@ -131,142 +142,201 @@ type BridgeConfig struct {
 	MaxConnections    int
 	ConnectionTimeout time.Duration
 	QueryTimeout      time.Duration
+
+	// Enrichment pipeline configuration. GeoIP*DBPath may be left empty to
+	// disable that provider; the pipeline still runs with whatever
+	// providers are configured.
+	GeoIPCityDBPath         string
+	GeoIPASNDBPath          string
+	GeoIPReloadInterval     time.Duration
+	TorExitListURL          string
+	TorRefreshInterval      time.Duration
+	SpamhausDropURL         string
+	SpamhausRefreshInterval time.Duration
+	AbuseIPDBAPIKey         string
+	OTXAPIKey               string
+	EnrichCacheSize         int
+	EnrichCacheTTL          time.Duration
+	EnrichNegativeTTL       time.Duration
+
+	// ConsumerDurablePrefix names the durable JetStream pull consumers the
+	// table batchers create (e.g. "<prefix>-threats"), so multiple bridge
+	// deployments against the same NATS account don't collide.
+	ConsumerDurablePrefix string
+
+	// RulesDir, if set, is loaded as a directory of Sigma YAML rules and
+	// watched for changes; leaving it empty runs the bridge with no
+	// in-stream detection rules at all.
+	RulesDir string
+
+	// OTLPEndpoint is the OTLP/gRPC collector address spans and metrics are
+	// exported to (e.g. "otel-collector:4317"). Left empty, tracing runs
+	// against a no-op tracer and no OTLP metric exporter is started.
+	OTLPEndpoint string
+	// TraceSamplingRatio is the fraction of traces recorded, in [0,1].
+	TraceSamplingRatio float64
+
+	// SyslogTCPListenAddr/SyslogUDPListenAddr, if set, start a raw RFC5424
+	// syslog listener (see parsers.SyslogListener) so an agent can push
+	// events directly without going through NATS. Leaving one empty
+	// disables that protocol; leaving both empty disables the listener
+	// entirely.
+	SyslogTCPListenAddr string
+	SyslogUDPListenAddr string
+
+	// EventEnricherTimeout bounds a single EventEnrichmentChain enricher's
+	// Enrich call, so one slow provider can't stall the whole chain.
+	EventEnricherTimeout time.Duration
+
+	// TAXIICollectionURL, if set, is fetched as a STIX 2.1 bundle (a TAXII
+	// 2.1 collection's "objects" endpoint) for IOC matching against
+	// SourceIP/DestinationIP/FileHash. Left empty, the STIX/TAXII enricher
+	// is skipped entirely.
+	TAXIICollectionURL   string
+	TAXIIRefreshInterval time.Duration
+
+	// DLQSubjectPrefix is prepended to a failure reason (e.g.
+	// "clickhouse-connection", "schema-mismatch") to build the subject a
+	// poison or persistently-failing message is republished to, as
+	// "<prefix>.<reason>". ReplayDLQ drains these subjects back into the
+	// main pipeline once the underlying problem is fixed.
+	DLQSubjectPrefix string
+
+	// ShutdownTimeout bounds how long Shutdown waits for in-flight
+	// batchers to drain and the NATS connection to drain its
+	// subscriptions before it gives up and closes everything anyway, so
+	// a stuck ClickHouse/NATS server can't hang a SIGTERM forever.
+	ShutdownTimeout time.Duration
+}
+
+// Validate checks the batching-related fields table batchers depend on,
+// since a misconfigured BatchSize/MaxRetries would otherwise surface as a
+// confusing runtime panic or busy-loop instead of a clear startup error.
+func (c *BridgeConfig) Validate() error {
+	if c.BatchSize <= 0 {
+		return fmt.Errorf("BatchSize must be positive, got %d", c.BatchSize)
+	}
+	if c.BatchTimeout <= 0 {
+		return fmt.Errorf("BatchTimeout must be positive, got %v", c.BatchTimeout)
+	}
+	if c.MaxRetries < 0 {
+		return fmt.Errorf("MaxRetries must not be negative, got %d", c.MaxRetries)
+	}
+	if c.RetryDelay <= 0 {
+		return fmt.Errorf("RetryDelay must be positive, got %v", c.RetryDelay)
+	}
+	if c.ConsumerDurablePrefix == "" {
+		return fmt.Errorf("ConsumerDurablePrefix must not be empty")
+	}
+	if c.TraceSamplingRatio < 0 || c.TraceSamplingRatio > 1 {
+		return fmt.Errorf("TraceSamplingRatio must be between 0 and 1, got %v", c.TraceSamplingRatio)
+	}
+	if c.EventEnricherTimeout <= 0 {
+		return fmt.Errorf("EventEnricherTimeout must be positive, got %v", c.EventEnricherTimeout)
+	}
+	if c.DLQSubjectPrefix == "" {
+		return fmt.Errorf("DLQSubjectPrefix must not be empty")
+	}
+	if c.ShutdownTimeout <= 0 {
+		return fmt.Errorf("ShutdownTimeout must be positive, got %v", c.ShutdownTimeout)
+	}
+	if c.EnableTLS {
+		for name, path := range map[string]string{
+			"TLSCertFile":   c.TLSCertFile,
+			"TLSKeyFile":    c.TLSKeyFile,
+			"TLSCACertFile": c.TLSCACertFile,
+		} {
+			if path == "" {
+				return fmt.Errorf("%s must be set when EnableTLS is true", name)
+			}
+			if _, err := os.Stat(path); err != nil {
+				return fmt.Errorf("%s %q: %w", name, path, err)
+			}
+		}
+	}
+	return nil
+}
+
+// providerStat tracks hit/miss/latency counters for one enrichment
+// provider, surfaced through SimpleStats.reportStats.
+type providerStat struct {
+	hits         uint64
+	misses       uint64
+	totalLatency time.Duration
 }
 
 // SimpleStats holds runtime statistics
 type SimpleStats struct {
-	eventsProcessed uint64
+	eventsProcessed  uint64
 	threatsProcessed uint64
-	errors          uint64
-	enrichments     uint64
-	lastUpdate      time.Time
-	mu              sync.RWMutex
+	errors           uint64
+	enrichments      uint64
+	cacheHits        uint64
+	cacheMisses      uint64
+	providers        map[string]*providerStat
+	ruleHits         map[string]uint64
+	lastUpdate       time.Time
+	mu               sync.RWMutex
 }
 
-// GeoIPEnricher handles IP enrichment
-type GeoIPEnricher struct {
-	cache map[string]*GeoIPData
-	mu    sync.RWMutex
+func newSimpleStats() *SimpleStats {
+	return &SimpleStats{
+		providers: make(map[string]*providerStat),
+		ruleHits:  make(map[string]uint64),
+	}
 }
 
-func NewGeoIPEnricher() *GeoIPEnricher {
-	return &GeoIPEnricher{
-		cache: make(map[string]*GeoIPData),
-	}
+func (s *SimpleStats) recordCacheHit() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cacheHits++
 }
 
-func (g *GeoIPEnricher) EnrichIP(ip string) *GeoIPData {
-	if ip == "" || ip == "0.0.0.0" || ip == "::1" {
-		return &GeoIPData{}
-	}
+func (s *SimpleStats) recordCacheMiss() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cacheMisses++
+}
 
-	g.mu.RLock()
-	if data, exists := g.cache[ip]; exists {
-		g.mu.RUnlock()
-		return data
+func (s *SimpleStats) providerStat(name string) *providerStat {
+	stat, ok := s.providers[name]
+	if !ok {
+		stat = &providerStat{}
+		s.providers[name] = stat
 	}
-	g.mu.RUnlock()
-
-	// Enhanced GeoIP lookup (replace with real service in production)
-	data := g.lookupIP(ip)
-
-	g.mu.Lock()
-	g.cache[ip] = data
-	g.mu.Unlock()
+	return stat
+}
 
-	return data
+func (s *SimpleStats) recordProviderHit(name string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat := s.providerStat(name)
+	stat.hits++
+	stat.totalLatency += latency
 }
 
-func (g *GeoIPEnricher) lookupIP(ip string) *GeoIPData {
-	// Enhanced IP lookup with more realistic data
-	parsedIP := net.ParseIP(ip)
-	if parsedIP == nil {
-		return &GeoIPData{}
-	}
-
-	// Private IP ranges
-	if parsedIP.IsPrivate() || parsedIP.IsLoopback() {
-		return &GeoIPData{
-			Country:   "PRIVATE",
-			City:      "Internal",
-			Region:    "Internal",
-			Latitude:  0.0,
-			Longitude: 0.0,
-			ASN:       0,
-			ASName:    "Private Network",
-			IsTor:     false,
-			Reputation: 100.0,
-		}
-	}
+func (s *SimpleStats) recordProviderMiss(name string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stat := s.providerStat(name)
+	stat.misses++
+	stat.totalLatency += latency
+}
 
-	// Enhanced mock data based on IP patterns
-	// In production, replace with MaxMind GeoIP2 or similar service
-	switch {
-	case strings.HasPrefix(ip, "8.8."):
-		return &GeoIPData{
-			Country:   "US",
-			City:      "Mountain View",
-			Region:    "CA",
-			Latitude:  37.4056,
-			Longitude: -122.0775,
-			ASN:       15169,
-			ASName:    "Google LLC",
-			IsTor:     false,
-			Reputation: 95.0,
-		}
-	case strings.HasPrefix(ip, "1.1."):
-		return &GeoIPData{
-			Country:   "US",
-			City:      "Los Angeles",
-			Region:    "CA",
-			Latitude:  34.0522,
-			Longitude: -118.2437,
-			ASN:       13335,
-			ASName:    "Cloudflare",
-			IsTor:     false,
-			Reputation: 90.0,
-		}
-	case strings.HasPrefix(ip, "208.67."):
-		return &GeoIPData{
-			Country:   "US",
-			City:      "San Francisco",
-			Region:    "CA",
-			Latitude:  37.7749,
-			Longitude: -122.4194,
-			ASN:       36692,
-			ASName:    "OpenDNS",
-			IsTor:     false,
-			Reputation: 85.0,
-		}
-	default:
-		// Generate realistic mock data based on IP hash
-		hash := 0
-		for _, char := range ip {
-			hash += int(char)
-		}
-		
-		countries := []string{"US", "CA", "GB", "DE", "FR", "JP", "AU", "BR", "IN", "CN"}
-		cities := []string{"New York", "London", "Berlin", "Paris", "Tokyo", "Sydney", "SÃ£o Paulo", "Mumbai", "Beijing", "Toronto"}
-		
-		countryIdx := hash % len(countries)
-		cityIdx := hash % len(cities)
-		
-		return &GeoIPData{
-			Country:   countries[countryIdx],
-			City:      cities[cityIdx],
-			Region:    "Unknown",
-			Latitude:  float64(hash%90) - 45.0,
-			Longitude: float64(hash%180) - 90.0,
-			ASN:       uint32(hash % 65535),
-			ASName:    "ISP Network",
-			IsTor:     hash%100 < 5, // 5% chance of being Tor
-			Reputation: float32(50 + hash%50), // 50-100 reputation score
-		}
-	}
+// recordRuleHit increments the hit counter for a Sigma rule that matched an
+// event, keyed by rule ID.
+func (s *SimpleStats) recordRuleHit(ruleID string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.ruleHits[ruleID]++
 }
 
 // NewSimpleBridge creates a new bridge instance with enhanced configuration
 func NewSimpleBridge(config *BridgeConfig) (*SimpleBridge, error) {
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid config: %w", err)
+	}
+
 	ctx, cancel := context.WithCancel(context.Background())
 
 	// Enhanced NATS connection with TLS support
@@ -354,18 +424,157 @@ func NewSimpleBridge(config *BridgeConfig) (*SimpleBridge, error) {
 		return nil, fmt.Errorf("failed to create tables: %w", err)
 	}
 
+	stats := newSimpleStats()
+
+	enricher, err := buildEnrichmentPipeline(config, stats)
+	if err != nil {
+		db.Close()
+		nc.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to build enrichment pipeline: %w", err)
+	}
+
+	var sigmaRules []*rules.Rule
+	if config.RulesDir != "" {
+		sigmaRules, err = rules.LoadDir(config.RulesDir)
+		if err != nil {
+			db.Close()
+			nc.Close()
+			cancel()
+			return nil, fmt.Errorf("failed to load sigma rules: %w", err)
+		}
+		log.Printf("ðŸ“‹ Loaded %d sigma rule(s) from %s", len(sigmaRules), config.RulesDir)
+	}
+
+	tracer, tracerShutdown, err := initTracer(ctx, config)
+	if err != nil {
+		db.Close()
+		nc.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to init tracer: %w", err)
+	}
+
+	meter, meterShutdown, err := initMeter(ctx, config)
+	if err != nil {
+		tracerShutdown(ctx)
+		db.Close()
+		nc.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to init meter: %w", err)
+	}
+
+	chInstruments, err := newClickhouseInstruments(meter)
+	if err != nil {
+		meterShutdown(ctx)
+		tracerShutdown(ctx)
+		db.Close()
+		nc.Close()
+		cancel()
+		return nil, fmt.Errorf("failed to create ClickHouse OTel instruments: %w", err)
+	}
+
+	metrics := newBridgeMetrics()
+	eventEnrichers := buildEventEnrichmentChain(config, enricher, metrics)
+
 	return &SimpleBridge{
-		nc:     nc,
-		js:     js,
-		db:     db,
-		ctx:    ctx,
-		cancel: cancel,
-		stats:  &SimpleStats{},
-		geoIP:  NewGeoIPEnricher(),
-		config: config,
+		nc:             nc,
+		js:             js,
+		db:             db,
+		ctx:            ctx,
+		cancel:         cancel,
+		stats:          stats,
+		enricher:       enricher,
+		eventEnrichers: eventEnrichers,
+		config:         config,
+		rulesEngine:    rules.NewEngine(sigmaRules),
+		tracer:         tracer,
+		metrics:        metrics,
+		chInstruments:  chInstruments,
+		tracerShutdown: tracerShutdown,
+		meterShutdown:  meterShutdown,
 	}, nil
 }
 
+// buildEventEnrichmentChain wires up the EventEnrichmentChain
+// enrichUltraSIEMEvent runs for every event: GeoIP and ASN (backed by the
+// same per-IP EnrichmentPipeline buildEnrichmentPipeline built), MITRE
+// ATT&CK tagging, STIX/TAXII IOC matching (if TAXIICollectionURL is set),
+// and compliance tagging.
+func buildEventEnrichmentChain(config *BridgeConfig, pipeline *EnrichmentPipeline, metrics *bridgeMetrics) *EventEnrichmentChain {
+	enrichers := []EventEnricher{
+		&geoIPEventEnricher{pipeline: pipeline},
+		&asnEventEnricher{pipeline: pipeline},
+		&mitreAttackEnricher{},
+	}
+
+	if config.TAXIICollectionURL != "" {
+		stix, err := NewSTIXTAXIIProvider(config.TAXIICollectionURL, config.TAXIIRefreshInterval)
+		if err != nil {
+			log.Printf("⚠️ STIX/TAXII collection unavailable, continuing without it: %v", err)
+		} else {
+			enrichers = append(enrichers, stix)
+		}
+	}
+
+	enrichers = append(enrichers, &complianceTagEnricher{})
+
+	return NewEventEnrichmentChain(enrichers, config.EventEnricherTimeout, metrics)
+}
+
+// buildEnrichmentPipeline wires up an EnrichmentPipeline from config: a
+// MaxMind provider (if mmdb paths are set), a Tor exit-node list and a
+// Spamhaus DROP list (if their URLs are set), and per-IP threat-intel
+// feeds for any API key that's configured. A bridge with none of these set
+// still gets a pipeline, just one that only ever returns private-IP/empty
+// results.
+func buildEnrichmentPipeline(config *BridgeConfig, stats *SimpleStats) (*EnrichmentPipeline, error) {
+	var providers []Enricher
+
+	if config.GeoIPCityDBPath != "" || config.GeoIPASNDBPath != "" {
+		maxmind, err := NewMaxMindProvider(config.GeoIPCityDBPath, config.GeoIPASNDBPath, config.GeoIPReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, maxmind)
+	}
+
+	if config.TorExitListURL != "" {
+		tor, err := NewCIDRListProvider("tor-exit", config.TorExitListURL, "tor-exit", 10.0, true, config.TorRefreshInterval)
+		if err != nil {
+			log.Printf("⚠️ Tor exit list unavailable, continuing without it: %v", err)
+		} else {
+			providers = append(providers, tor)
+		}
+	}
+
+	if config.SpamhausDropURL != "" {
+		spamhaus, err := NewCIDRListProvider("spamhaus-drop", config.SpamhausDropURL, "spamhaus-drop", 0.0, false, config.SpamhausRefreshInterval)
+		if err != nil {
+			log.Printf("⚠️ Spamhaus DROP list unavailable, continuing without it: %v", err)
+		} else {
+			providers = append(providers, spamhaus)
+		}
+	}
+
+	if config.AbuseIPDBAPIKey != "" {
+		providers = append(providers, NewHTTPThreatIntelProvider(
+			"abuseipdb",
+			"https://api.abuseipdb.com/api/v2/check?ipAddress=%s",
+			"Key", config.AbuseIPDBAPIKey, parseAbuseIPDB,
+		))
+	}
+
+	if config.OTXAPIKey != "" {
+		providers = append(providers, NewHTTPThreatIntelProvider(
+			"otx",
+			"https://otx.alienvault.com/api/v1/indicators/IPv4/%s/general",
+			"X-OTX-API-KEY", config.OTXAPIKey, parseOTX,
+		))
+	}
+
+	return NewEnrichmentPipeline(providers, config.EnrichCacheSize, config.EnrichCacheTTL, config.EnrichNegativeTTL, stats), nil
+}
+
 // createTLSConfig creates TLS configuration for NATS
 func createTLSConfig(config *BridgeConfig) (*tls.Config, error) {
 	cert, err := tls.LoadX509KeyPair(config.TLSCertFile, config.TLSKeyFile)
@@ -536,54 +745,59 @@ func createTableIfNotExists(ctx context.Context, db driver.Conn) error {
 func (b *SimpleBridge) Start() error {
 	log.Println("ðŸš€ Starting Ultra SIEM Enhanced Bridge...")
 
-	// Subscribe to threats with timeout
-	threatsSub, err := b.js.Subscribe("ultra_siem.threats", b.handleThreatEvent)
+	threatsBatcher, err := b.startTableBatcher("threats", "ultra_siem.threats", b.config.ConsumerDurablePrefix+"-threats",
+		"INSERT INTO ultra_siem.threats", b.buildThreatsRow)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to ultra_siem.threats: %w", err)
+		return fmt.Errorf("failed to start threats batcher: %w", err)
 	}
-	defer threatsSub.Unsubscribe()
+	b.batchers = append(b.batchers, threatsBatcher)
 
-	// Subscribe to events with timeout
-	eventsSub, err := b.js.Subscribe("ultra_siem.events", b.handleUltraSIEMEvent)
+	eventsBatcher, err := b.startTableBatcher("events", "ultra_siem.events", b.config.ConsumerDurablePrefix+"-events",
+		"INSERT INTO ultra_siem.events", b.buildEventsRow)
 	if err != nil {
-		return fmt.Errorf("failed to subscribe to ultra_siem.events: %w", err)
+		return fmt.Errorf("failed to start events batcher: %w", err)
+	}
+	b.batchers = append(b.batchers, eventsBatcher)
+
+	if err := b.startRawIngestBatchers(); err != nil {
+		return err
 	}
-	defer eventsSub.Unsubscribe()
+	b.startSyslogListener()
 
 	// Start statistics reporting
 	go b.reportStats()
 
-	log.Println("âœ… Ultra SIEM Enhanced Bridge started successfully")
-	log.Println("ðŸ“¡ Listening for events on: ultra_siem.threats, ultra_siem.events")
+	if b.config.EnableMetrics {
+		go b.startMetricsServer()
+	}
 
-	// Keep the service running
-	select {
-	case <-b.ctx.Done():
-		return b.ctx.Err()
+	for _, tb := range b.batchers {
+		go tb.reportConsumerLag(b.ctx)
 	}
-}
 
-func (b *SimpleBridge) handleThreatEvent(msg *nats.Msg) {
-	var event ThreatEvent
-	if err := json.Unmarshal(msg.Data, &event); err != nil {
-		log.Printf("âŒ Error unmarshaling threat event: %v", err)
-		b.updateErrorStats()
-		return
+	if b.config.RulesDir != "" {
+		go func() {
+			if err := rules.Watch(b.config.RulesDir, b.rulesEngine, b.ctx.Done()); err != nil {
+				log.Printf("âš ï¸ sigma rules watcher stopped: %v", err)
+			}
+		}()
 	}
 
-	// Process the event with timeout
-	ctxTimeout, cancel := context.WithTimeout(b.ctx, 5*time.Second)
-	defer cancel()
+	log.Println("âœ… Ultra SIEM Enhanced Bridge started successfully")
+	log.Println("ðŸ“¡ Pulling events from: ultra_siem.threats, ultra_siem.events")
 
-	if err := b.processThreatEvent(ctxTimeout, &event); err != nil {
-		log.Printf("âŒ Error processing threat event: %v", err)
-		b.updateErrorStats()
-		return
-	}
+	<-b.ctx.Done()
+	b.waitForBatchers()
+	return b.ctx.Err()
+}
 
-	b.updateThreatStats()
-	log.Printf("âœ… Processed threat: %s from %s (confidence: %.2f)", 
-		event.ThreatType, event.SourceIP, event.Confidence)
+// waitForBatchers blocks until every table batcher has drained its
+// in-flight batch and returned, so Shutdown never closes the ClickHouse/NATS
+// connections out from under a flush in progress.
+func (b *SimpleBridge) waitForBatchers() {
+	for _, tb := range b.batchers {
+		tb.wg.Wait()
+	}
 }
 
 func (b *SimpleBridge) handleSystemEvent(msg *nats.Msg) {
@@ -608,107 +822,6 @@ func (b *SimpleBridge) handleSystemEvent(msg *nats.Msg) {
 	log.Printf("ðŸ“Š Processed system event: %s from %s", event.EventType, event.Source)
 }
 
-func (b *SimpleBridge) handleUltraSIEMEvent(msg *nats.Msg) {
-	var event UltraSIEMEvent
-	if err := json.Unmarshal(msg.Data, &event); err != nil {
-		log.Printf("âŒ Error unmarshaling UltraSIEM event: %v", err)
-		b.updateErrorStats()
-		return
-	}
-
-	// Enhanced enrichment (GeoIP, etc.)
-	b.enrichUltraSIEMEvent(&event)
-
-	ctxTimeout, cancel := context.WithTimeout(b.ctx, 5*time.Second)
-	defer cancel()
-
-	if err := b.processUltraSIEMEvent(ctxTimeout, &event); err != nil {
-		log.Printf("âŒ Error processing UltraSIEM event: %v", err)
-		b.updateErrorStats()
-		return
-	}
-
-	b.updateEventStats()
-	log.Printf("âœ… Processed event: %s from %s (user: %s, host: %s)", event.EventType, event.SourceIP, event.User, event.Hostname)
-}
-
-func (b *SimpleBridge) processThreatEvent(ctx context.Context, event *ThreatEvent) error {
-	// Enrich threat event with GeoIP
-	geoData := b.geoIP.EnrichIP(event.SourceIP)
-
-	// Prepare batch insert with timeout
-	batch, err := b.db.PrepareBatch(ctx, "INSERT INTO ultra_siem.threats")
-	if err != nil {
-		return fmt.Errorf("error preparing batch: %w", err)
-	}
-
-	// Add event to batch with enhanced fields
-	err = batch.Append(
-		generateUUID(),
-		time.Unix(event.Timestamp, 0),
-		event.ThreatType,
-		float32(event.Confidence),
-		event.SourceIP,
-		"", // destination_ip
-		0,  // source_port
-		0,  // destination_port
-		"", // protocol
-		event.Payload,
-		fmt.Sprintf("{\"source_ip\":\"%s\",\"confidence\":%.2f}", event.SourceIP, event.Confidence),
-		uint8(event.Severity),
-		"new",
-		"", // user
-		"", // hostname
-		"", // process
-		0,  // process_id
-		"", // log_source
-		"", // raw_message
-		0,  // event_id
-		"", // session_id
-		"", // user_agent
-		"", // request_uri
-		"", // http_method
-		0,  // response_code
-		0,  // bytes_transferred
-		"", // command_line
-		"", // file_hash
-		"", // registry_key
-		"", // network_connection
-		"", // dns_query
-		"", // certificate_info
-		"", // threat_intelligence_match
-		0.0, // ml_score
-		0,   // false_positive
-		"",  // analyst_notes
-		"",  // remediation_status
-		"",  // incident_id
-		"",  // compliance_tags
-		"",  // data_classification
-		"",  // retention_policy
-		1,   // encryption_status
-		"",  // audit_trail
-		geoData.Country,
-		geoData.City,
-		geoData.Region,
-		geoData.Latitude,
-		geoData.Longitude,
-		geoData.ASN,
-		geoData.ASName,
-		boolToUint8(geoData.IsTor),
-		geoData.Reputation,
-	)
-	if err != nil {
-		return fmt.Errorf("error appending to batch: %w", err)
-	}
-
-	// Send batch with timeout
-	if err := batch.Send(); err != nil {
-		return fmt.Errorf("error sending batch: %w", err)
-	}
-
-	return nil
-}
-
 func (b *SimpleBridge) processSystemEvent(ctx context.Context, event *SystemEvent) error {
 	// Publish event to NATS ultra_siem.events
 	data, err := json.Marshal(event)
@@ -739,21 +852,21 @@ func (b *SimpleBridge) processSystemEvent(ctx context.Context, event *SystemEven
 		0,  // process_id
 		"", // log_source
 		event.Message,
-		"", // raw_message
-		0,  // event_id
-		"", // session_id
-		"", // user_agent
-		"", // request_uri
-		"", // http_method
-		0,  // response_code
-		0,  // bytes_transferred
-		"", // command_line
-		"", // file_hash
-		"", // registry_key
-		"", // network_connection
-		"", // dns_query
-		"", // certificate_info
-		"", // threat_intelligence_match
+		"",  // raw_message
+		0,   // event_id
+		"",  // session_id
+		"",  // user_agent
+		"",  // request_uri
+		"",  // http_method
+		0,   // response_code
+		0,   // bytes_transferred
+		"",  // command_line
+		"",  // file_hash
+		"",  // registry_key
+		"",  // network_connection
+		"",  // dns_query
+		"",  // certificate_info
+		"",  // threat_intelligence_match
 		0.0, // ml_score
 		0,   // false_positive
 		"",  // analyst_notes
@@ -787,86 +900,6 @@ func (b *SimpleBridge) processSystemEvent(ctx context.Context, event *SystemEven
 	return nil
 }
 
-func (b *SimpleBridge) processUltraSIEMEvent(ctx context.Context, event *UltraSIEMEvent) error {
-	// Enrich event with GeoIP data
-	geoData := b.geoIP.EnrichIP(event.SourceIP)
-	b.updateEnrichmentStats()
-
-	batch, err := b.db.PrepareBatch(ctx, "INSERT INTO ultra_siem.events")
-	if err != nil {
-		return fmt.Errorf("error preparing batch: %w", err)
-	}
-
-	metadataJSON, _ := json.Marshal(event.Metadata)
-	complianceTagsJSON, _ := json.Marshal(event.ComplianceTags)
-
-	err = batch.Append(
-		generateUUID(),
-		time.Unix(event.Timestamp, 0),
-		event.SourceIP,
-		event.DestinationIP,
-		event.SourcePort,
-		event.DestinationPort,
-		event.Protocol,
-		event.EventType,
-		uint8(event.Severity),
-		event.User,
-		event.Hostname,
-		event.Process,
-		event.ProcessID,
-		event.LogSource,
-		event.Message,
-		event.RawMessage,
-		event.EventID,
-		event.SessionID,
-		event.UserAgent,
-		event.RequestURI,
-		event.HTTPMethod,
-		event.ResponseCode,
-		event.BytesTransferred,
-		event.CommandLine,
-		event.FileHash,
-		event.RegistryKey,
-		event.NetworkConnection,
-		event.DNSQuery,
-		event.CertificateInfo,
-		event.ThreatIntelligenceMatch,
-		event.MLScore,
-		boolToUint8(event.FalsePositive),
-		event.AnalystNotes,
-		event.RemediationStatus,
-		event.IncidentID,
-		string(complianceTagsJSON),
-		event.DataClassification,
-		event.RetentionPolicy,
-		boolToUint8(event.EncryptionStatus),
-		event.AuditTrail,
-		string(metadataJSON),
-		geoData.Country,
-		geoData.City,
-		geoData.Region,
-		geoData.Latitude,
-		geoData.Longitude,
-		geoData.ASN,
-		geoData.ASName,
-		boolToUint8(geoData.IsTor),
-		geoData.Reputation,
-	)
-	if err != nil {
-		return fmt.Errorf("error appending to batch: %w", err)
-	}
-	if err := batch.Send(); err != nil {
-		return fmt.Errorf("error sending batch: %w", err)
-	}
-
-	// Publish enriched event to NATS for Rust core analysis
-	data, err := json.Marshal(event)
-	if err == nil {
-		b.nc.Publish("ultra_siem.events", data)
-	}
-	return nil
-}
-
 func (b *SimpleBridge) updateEventStats() {
 	b.stats.mu.Lock()
 	defer b.stats.mu.Unlock()
@@ -879,6 +912,7 @@ func (b *SimpleBridge) updateThreatStats() {
 	defer b.stats.mu.Unlock()
 	b.stats.threatsProcessed++
 	b.stats.lastUpdate = time.Now()
+	b.metrics.threatsDetected.Inc()
 }
 
 func (b *SimpleBridge) updateErrorStats() {
@@ -893,6 +927,7 @@ func (b *SimpleBridge) updateEnrichmentStats() {
 	defer b.stats.mu.Unlock()
 	b.stats.enrichments++
 	b.stats.lastUpdate = time.Now()
+	b.metrics.enrichmentsPerformed.Inc()
 }
 
 func (b *SimpleBridge) reportStats() {
@@ -907,20 +942,109 @@ func (b *SimpleBridge) reportStats() {
 			threats := b.stats.threatsProcessed
 			errors := b.stats.errors
 			enrichments := b.stats.enrichments
+			cacheHits := b.stats.cacheHits
+			cacheMisses := b.stats.cacheMisses
+			providerSummary := make([]string, 0, len(b.stats.providers))
+			for name, stat := range b.stats.providers {
+				total := stat.hits + stat.misses
+				avgLatency := time.Duration(0)
+				if total > 0 {
+					avgLatency = stat.totalLatency / time.Duration(total)
+				}
+				providerSummary = append(providerSummary, fmt.Sprintf("%s(hits=%d,misses=%d,avg=%s)", name, stat.hits, stat.misses, avgLatency))
+			}
+			ruleHitSummary := make(map[string]uint64, len(b.stats.ruleHits))
+			for ruleID, hits := range b.stats.ruleHits {
+				ruleHitSummary[ruleID] = hits
+			}
 			b.stats.mu.RUnlock()
 
-			log.Printf("ðŸ“Š Stats: Events=%d, Threats=%d, Enrichments=%d, Errors=%d", events, threats, enrichments, errors)
+			log.Printf("ðŸ“Š Stats: Events=%d, Threats=%d, Enrichments=%d, Errors=%d, CacheHits=%d, CacheMisses=%d, Providers=%v, RuleHits=%v",
+				events, threats, enrichments, errors, cacheHits, cacheMisses, providerSummary, ruleHitSummary)
 		case <-b.ctx.Done():
 			return
 		}
 	}
 }
 
+// Shutdown stops accepting new work and blocks, up to ShutdownTimeout,
+// until every table batcher has flushed its pending batch and the NATS
+// connection has drained its subscriptions, so a SIGTERM never loses
+// whatever was buffered mid-batch. Past the timeout it closes everything
+// anyway rather than hang forever on a stuck ClickHouse/NATS server.
 func (b *SimpleBridge) Shutdown() {
 	log.Println("ðŸ›‘ Shutting down Ultra SIEM Enhanced Bridge...")
 	b.cancel()
-	b.nc.Close()
+
+	deadline := time.Now().Add(b.config.ShutdownTimeout)
+
+	drained := make(chan struct{})
+	go func() {
+		b.waitForBatchers()
+		close(drained)
+	}()
+	select {
+	case <-drained:
+	case <-time.After(time.Until(deadline)):
+		log.Printf("âš ï¸ timed out after %v waiting for batchers to drain", b.config.ShutdownTimeout)
+	}
+
+	if err := b.nc.Drain(); err != nil {
+		log.Printf("âš ï¸ NATS drain failed, closing anyway: %v", err)
+		b.nc.Close()
+	} else {
+		for !b.nc.IsClosed() && time.Now().Before(deadline) {
+			time.Sleep(50 * time.Millisecond)
+		}
+		if !b.nc.IsClosed() {
+			log.Printf("âš ï¸ NATS drain did not finish within %v, closing anyway", b.config.ShutdownTimeout)
+			b.nc.Close()
+		}
+	}
+
 	b.db.Close()
+	b.meterShutdown(context.Background())
+	b.tracerShutdown(context.Background())
+	log.Println("âœ… shutdown complete")
+}
+
+// Reload rebuilds the enrichment pipeline and EventEnrichmentChain from
+// the bridge's current config, re-reading GeoIP/threat-intel databases and
+// restarting their refresh loops, without restarting the process or
+// disrupting in-flight batching. Triggered by SIGHUP.
+func (b *SimpleBridge) Reload() error {
+	log.Println("ðŸ”„ reloading enrichment pipeline...")
+
+	enricher, err := buildEnrichmentPipeline(b.config, b.stats)
+	if err != nil {
+		return fmt.Errorf("rebuilding enrichment pipeline: %w", err)
+	}
+	eventEnrichers := buildEventEnrichmentChain(b.config, enricher, b.metrics)
+
+	b.mu.Lock()
+	b.enricher = enricher
+	b.eventEnrichers = eventEnrichers
+	b.mu.Unlock()
+
+	log.Println("âœ… enrichment pipeline reloaded")
+	return nil
+}
+
+// RunMigrations applies the migrations package's pending schema changes and
+// reconciles ultra_siem.events' and ultra_siem.threats' columns against the
+// current UltraSIEMEvent/ThreatEvent structs, for the bridge binary
+// --migrate flag.
+func (b *SimpleBridge) RunMigrations(ctx context.Context) error {
+	if err := migrations.Apply(ctx, b.db); err != nil {
+		return fmt.Errorf("applying migrations: %w", err)
+	}
+	if err := migrations.DiffAddColumns(ctx, b.db, "events", migrations.StructColumns(UltraSIEMEvent{})); err != nil {
+		return fmt.Errorf("reconciling events columns: %w", err)
+	}
+	if err := migrations.DiffAddColumns(ctx, b.db, "threats", migrations.StructColumns(ThreatEvent{})); err != nil {
+		return fmt.Errorf("reconciling threats columns: %w", err)
+	}
+	return nil
 }
 
 // generateUUID generates a secure UUID for event identification
@@ -928,30 +1052,18 @@ func generateUUID() string {
 	return uuid.New().String()
 }
 
-// enrichUltraSIEMEvent enriches events with GeoIP and threat intelligence data
-func (b *SimpleBridge) enrichUltraSIEMEvent(event *UltraSIEMEvent) {
-	// Generate UUID if not present
+// enrichUltraSIEMEvent fills in the UUID/timestamp/defaults every event
+// needs, then runs the bridge's EventEnrichmentChain (GeoIP, ASN, MITRE
+// ATT&CK tagging, STIX/TAXII IOC matching, compliance tagging) over it.
+func (b *SimpleBridge) enrichUltraSIEMEvent(ctx context.Context, event *UltraSIEMEvent) {
 	if event.ID == "" {
 		event.ID = generateUUID()
 	}
 
-	// Enrich with GeoIP data
-	if event.SourceIP != "" {
-		geoData := b.geoIP.EnrichIP(event.SourceIP)
-		// Note: In production, you would map these to the actual database fields
-		// For now, we'll store them in metadata
-		if event.Metadata == nil {
-			event.Metadata = make(map[string]interface{})
-		}
-		event.Metadata["geoip"] = geoData
-	}
-
-	// Add timestamp if not present
 	if event.Timestamp == 0 {
 		event.Timestamp = time.Now().UnixNano() / int64(time.Millisecond)
 	}
 
-	// Add default values for required fields
 	if event.LogSource == "" {
 		event.LogSource = "ultra_siem_bridge"
 	}
@@ -964,24 +1076,15 @@ func (b *SimpleBridge) enrichUltraSIEMEvent(event *UltraSIEMEvent) {
 		event.RetentionPolicy = "standard"
 	}
 
-	// Add compliance tags based on event type
 	if event.ComplianceTags == nil {
 		event.ComplianceTags = []string{}
 	}
 
-	// Add relevant compliance tags
-	switch event.EventType {
-	case "authentication", "login", "logout":
-		event.ComplianceTags = append(event.ComplianceTags, "SOX", "PCI-DSS", "GDPR")
-	case "file_access", "data_access":
-		event.ComplianceTags = append(event.ComplianceTags, "SOX", "HIPAA", "GDPR")
-	case "network_connection", "firewall":
-		event.ComplianceTags = append(event.ComplianceTags, "PCI-DSS", "NIST")
-	case "threat_detection", "malware":
-		event.ComplianceTags = append(event.ComplianceTags, "NIST", "ISO27001")
-	}
+	b.mu.RLock()
+	eventEnrichers := b.eventEnrichers
+	b.mu.RUnlock()
+	eventEnrichers.Run(ctx, event)
 
-	// Update enrichment statistics
 	b.updateEnrichmentStats()
 }
 
@@ -994,144 +1097,48 @@ func boolToUint8(b bool) uint8 {
 }
 
 // Enhanced main function with comprehensive error handling and monitoring
-func main() {
-	log.Println("ðŸš€ Ultra SIEM Enhanced Bridge Starting...")
-
-	// Define default configuration
-	config := &BridgeConfig{
+// defaultBridgeConfig returns the bridge's built-in defaults, the bottom of
+// the config precedence chain (defaults < config file < env vars < CLI
+// flags) runCLI builds on top of.
+func defaultBridgeConfig() *BridgeConfig {
+	return &BridgeConfig{
 		NATSUrl:           "nats://nats:4222",
 		ClickHouseURL:     "clickhouse:9000",
 		ClickHouseUser:    "admin",
 		ClickHousePass:    "admin",
 		ClickHouseDB:      "ultra_siem",
 		BatchSize:         100,
-		BatchTimeout:     5 * time.Second,
-		MaxRetries:       3,
-		RetryDelay:       1 * time.Second,
-		EnableTLS:        false,
-		TLSCertFile:      "",
-		TLSKeyFile:       "",
-		TLSCACertFile:    "",
-		EnableMetrics:    false,
-		MetricsPort:      8080,
-		LogLevel:         "info",
-		MaxConnections:   10,
+		BatchTimeout:      5 * time.Second,
+		MaxRetries:        3,
+		RetryDelay:        1 * time.Second,
+		EnableTLS:         false,
+		TLSCertFile:       "",
+		TLSKeyFile:        "",
+		TLSCACertFile:     "",
+		EnableMetrics:     false,
+		MetricsPort:       8080,
+		LogLevel:          "info",
+		MaxConnections:    10,
 		ConnectionTimeout: 10 * time.Second,
-		QueryTimeout:     60 * time.Second,
-	}
-
-	// Override with environment variables if available
-	if natsURL := os.Getenv("NATS_URL"); natsURL != "" {
-		config.NATSUrl = natsURL
-	}
-	if clickhouseURL := os.Getenv("CLICKHOUSE_URL"); clickhouseURL != "" {
-		config.ClickHouseURL = clickhouseURL
-	}
-	if clickhouseUser := os.Getenv("CLICKHOUSE_USER"); clickhouseUser != "" {
-		config.ClickHouseUser = clickhouseUser
-	}
-	if clickhousePass := os.Getenv("CLICKHOUSE_PASS"); clickhousePass != "" {
-		config.ClickHousePass = clickhousePass
-	}
-	if clickhouseDB := os.Getenv("CLICKHOUSE_DB"); clickhouseDB != "" {
-		config.ClickHouseDB = clickhouseDB
-	}
-	if batchSize := os.Getenv("BATCH_SIZE"); batchSize != "" {
-		if size, err := strconv.Atoi(batchSize); err == nil {
-			config.BatchSize = size
-		}
-	}
-	if batchTimeout := os.Getenv("BATCH_TIMEOUT"); batchTimeout != "" {
-		if timeout, err := time.ParseDuration(batchTimeout); err == nil {
-			config.BatchTimeout = timeout
-		}
-	}
-	if maxRetries := os.Getenv("MAX_RETRIES"); maxRetries != "" {
-		if retries, err := strconv.Atoi(maxRetries); err == nil {
-			config.MaxRetries = retries
-		}
-	}
-	if retryDelay := os.Getenv("RETRY_DELAY"); retryDelay != "" {
-		if delay, err := time.ParseDuration(retryDelay); err == nil {
-			config.RetryDelay = delay
-		}
-	}
-	if enableTLS := os.Getenv("ENABLE_TLS"); enableTLS != "" {
-		if tls, err := strconv.ParseBool(enableTLS); err == nil {
-			config.EnableTLS = tls
-		}
-	}
-	if tlsCertFile := os.Getenv("TLS_CERT_FILE"); tlsCertFile != "" {
-		config.TLSCertFile = tlsCertFile
-	}
-	if tlsKeyFile := os.Getenv("TLS_KEY_FILE"); tlsKeyFile != "" {
-		config.TLSKeyFile = tlsKeyFile
-	}
-	if tlsCACertFile := os.Getenv("TLS_CA_CERT_FILE"); tlsCACertFile != "" {
-		config.TLSCACertFile = tlsCACertFile
-	}
-	if enableMetrics := os.Getenv("ENABLE_METRICS"); enableMetrics != "" {
-		if metrics, err := strconv.ParseBool(enableMetrics); err == nil {
-			config.EnableMetrics = metrics
-		}
-	}
-	if metricsPort := os.Getenv("METRICS_PORT"); metricsPort != "" {
-		if port, err := strconv.Atoi(metricsPort); err == nil {
-			config.MetricsPort = port
-		}
-	}
-	if logLevel := os.Getenv("LOG_LEVEL"); logLevel != "" {
-		config.LogLevel = logLevel
-	}
-	if maxConnections := os.Getenv("MAX_CONNECTIONS"); maxConnections != "" {
-		if connections, err := strconv.Atoi(maxConnections); err == nil {
-			config.MaxConnections = connections
-		}
+		QueryTimeout:      60 * time.Second,
+
+		GeoIPReloadInterval:     10 * time.Minute,
+		TorRefreshInterval:      1 * time.Hour,
+		SpamhausRefreshInterval: 1 * time.Hour,
+		EnrichCacheSize:         100000,
+		EnrichCacheTTL:          1 * time.Hour,
+		EnrichNegativeTTL:       5 * time.Minute,
+		ConsumerDurablePrefix:   "ultra-siem-bridge",
+		TraceSamplingRatio:      1.0,
+		EventEnricherTimeout:    2 * time.Second,
+		TAXIIRefreshInterval:    1 * time.Hour,
+		DLQSubjectPrefix:        "ultra_siem.dlq",
+		ShutdownTimeout:         30 * time.Second,
 	}
-	if connectionTimeout := os.Getenv("CONNECTION_TIMEOUT"); connectionTimeout != "" {
-		if timeout, err := time.ParseDuration(connectionTimeout); err == nil {
-			config.ConnectionTimeout = timeout
-		}
-	}
-	if queryTimeout := os.Getenv("QUERY_TIMEOUT"); queryTimeout != "" {
-		if timeout, err := time.ParseDuration(queryTimeout); err == nil {
-			config.QueryTimeout = timeout
-		}
-	}
-
-	// Log configuration (without sensitive data)
-	log.Printf("ðŸ“‹ Configuration:")
-	log.Printf("   NATS URL: %s", config.NATSUrl)
-	log.Printf("   ClickHouse URL: %s", config.ClickHouseURL)
-	log.Printf("   ClickHouse DB: %s", config.ClickHouseDB)
-	log.Printf("   Batch Size: %d", config.BatchSize)
-	log.Printf("   Batch Timeout: %v", config.BatchTimeout)
-	log.Printf("   Max Retries: %d", config.MaxRetries)
-	log.Printf("   Enable TLS: %v", config.EnableTLS)
-	log.Printf("   Enable Metrics: %v", config.EnableMetrics)
-	log.Printf("   Max Connections: %d", config.MaxConnections)
-
-	bridge, err := NewSimpleBridge(config)
-	if err != nil {
-		log.Fatalf("âŒ Failed to create bridge: %v", err)
-	}
-
-	// Set up graceful shutdown
-	sigChan := make(chan os.Signal, 1)
-	// signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-
-	go func() {
-		<-sigChan
-		log.Println("ðŸ›‘ Shutdown signal received, stopping bridge...")
-		bridge.Shutdown()
-		os.Exit(0)
-	}()
+}
 
-	// Start the bridge
-	if err := bridge.Start(); err != nil {
-		log.Fatalf("âŒ Failed to start bridge: %v", err)
+func main() {
+	if err := runCLI(os.Args); err != nil {
+		log.Fatalf("âŒ %v", err)
 	}
-
-	// Keep the main goroutine alive
-	select {}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,103 @@
+package migrations
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// chTypeForKind maps a Go struct field's Kind to the ClickHouse column type
+// a diff-generated ADD COLUMN uses for it. The mapping is by Kind alone: it
+// does not reproduce per-column choices of the hand-written CREATE TABLE
+// schema (e.g. timestamp DateTime, severity UInt8), which only matters for
+// columns that schema doesn't already have.
+func chTypeForKind(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.Bool:
+		return "UInt8"
+	case reflect.Int, reflect.Int64:
+		return "Int64"
+	case reflect.Int32:
+		return "Int32"
+	case reflect.Uint16:
+		return "UInt16"
+	case reflect.Uint32:
+		return "UInt32"
+	case reflect.Uint64:
+		return "UInt64"
+	case reflect.Float32:
+		return "Float32"
+	case reflect.Float64:
+		return "Float64"
+	default:
+		return "String"
+	}
+}
+
+// columnName extracts the ClickHouse column name from a struct field's json
+// tag, stripping ",omitempty", and falls back to the lowercased field name
+// for a field with no tag.
+func columnName(f reflect.StructField) string {
+	name := strings.Split(f.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return strings.ToLower(f.Name)
+	}
+	return name
+}
+
+// StructColumns reflects over v (a struct value, not a pointer) and returns
+// the column name/ClickHouse type pairs it implies, keyed by column name.
+func StructColumns(v interface{}) map[string]string {
+	t := reflect.TypeOf(v)
+	out := make(map[string]string, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		out[columnName(f)] = chTypeForKind(f.Type)
+	}
+	return out
+}
+
+// existingColumns queries ClickHouse's system.columns for table's current
+// column names.
+func existingColumns(ctx context.Context, db driver.Conn, table string) (map[string]bool, error) {
+	rows, err := db.Query(ctx, "SELECT name FROM system.columns WHERE database = currentDatabase() AND table = ?", table)
+	if err != nil {
+		return nil, fmt.Errorf("querying system.columns for %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]bool)
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, fmt.Errorf("scanning system.columns row: %w", err)
+		}
+		out[name] = true
+	}
+	return out, rows.Err()
+}
+
+// DiffAddColumns compares want (as produced by StructColumns) against
+// table's actual columns in ClickHouse and issues an ADD COLUMN IF NOT
+// EXISTS for each one missing, so a struct gaining a new field doesn't
+// silently drift out of sync with the schema.
+func DiffAddColumns(ctx context.Context, db driver.Conn, table string, want map[string]string) error {
+	existing, err := existingColumns(ctx, db, table)
+	if err != nil {
+		return err
+	}
+
+	for name, chType := range want {
+		if existing[name] {
+			continue
+		}
+		query := fmt.Sprintf("ALTER TABLE ultra_siem.%s ADD COLUMN IF NOT EXISTS %s %s", table, name, chType)
+		if err := db.Exec(ctx, query); err != nil {
+			return fmt.Errorf("adding column %s to %s: %w", name, table, err)
+		}
+	}
+	return nil
+}
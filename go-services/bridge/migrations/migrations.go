@@ -0,0 +1,139 @@
+// Package migrations tracks schema changes to the ultra_siem ClickHouse
+// tables across bridge releases. createTableIfNotExists only ever runs a
+// CREATE TABLE IF NOT EXISTS, so it can't evolve a table that already
+// exists; Apply is what carries an existing deployment forward.
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// Migration is one forward-only schema change, applied in Version order.
+// SQL must be idempotent (IF NOT EXISTS, MODIFY COLUMN/TTL) since Apply may
+// run against a table an older bridge already created or partially altered.
+type Migration struct {
+	Version int
+	Name    string
+	SQL     string
+}
+
+// Checksum hashes SQL so Apply can detect a migration that was edited after
+// it already shipped, instead of silently re-running or skipping it.
+func (m Migration) Checksum() string {
+	sum := sha256.Sum256([]byte(m.SQL))
+	return hex.EncodeToString(sum[:])
+}
+
+// All is the ordered list of migrations Apply runs. Append new entries with
+// the next Version; never edit a migration once it has shipped, since that
+// changes its Checksum out from under deployments that already applied it.
+var All = []Migration{
+	{
+		Version: 1,
+		Name:    "low_cardinality_columns",
+		SQL: `
+ALTER TABLE ultra_siem.threats
+	MODIFY COLUMN threat_type LowCardinality(String),
+	MODIFY COLUMN protocol LowCardinality(String),
+	MODIFY COLUMN log_source LowCardinality(String),
+	MODIFY COLUMN geoip_country LowCardinality(String);
+ALTER TABLE ultra_siem.events
+	MODIFY COLUMN event_type LowCardinality(String),
+	MODIFY COLUMN protocol LowCardinality(String),
+	MODIFY COLUMN log_source LowCardinality(String),
+	MODIFY COLUMN geoip_country LowCardinality(String);
+`,
+	},
+	{
+		Version: 2,
+		Name:    "retention_policy_ttl",
+		SQL: `
+ALTER TABLE ultra_siem.threats MODIFY TTL timestamp + toIntervalDay(
+	multiIf(retention_policy = 'short', 30, retention_policy = 'extended', 365, 90)
+);
+ALTER TABLE ultra_siem.events MODIFY TTL timestamp + toIntervalDay(
+	multiIf(retention_policy = 'short', 30, retention_policy = 'extended', 365, 90)
+);
+`,
+	},
+}
+
+// EnsureMigrationsTable creates the ultra_siem.schema_migrations tracking
+// table if it doesn't already exist.
+func EnsureMigrationsTable(ctx context.Context, db driver.Conn) error {
+	query := `
+	CREATE TABLE IF NOT EXISTS ultra_siem.schema_migrations (
+		version UInt32,
+		name String,
+		checksum String,
+		applied_at DateTime
+	) ENGINE = MergeTree()
+	ORDER BY version
+	`
+	if err := db.Exec(ctx, query); err != nil {
+		return fmt.Errorf("creating schema_migrations table: %w", err)
+	}
+	return nil
+}
+
+// applied returns the checksum recorded for every already-applied version.
+func applied(ctx context.Context, db driver.Conn) (map[int]string, error) {
+	rows, err := db.Query(ctx, "SELECT version, checksum FROM ultra_siem.schema_migrations")
+	if err != nil {
+		return nil, fmt.Errorf("querying schema_migrations: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[int]string)
+	for rows.Next() {
+		var version uint32
+		var checksum string
+		if err := rows.Scan(&version, &checksum); err != nil {
+			return nil, fmt.Errorf("scanning schema_migrations row: %w", err)
+		}
+		out[int(version)] = checksum
+	}
+	return out, rows.Err()
+}
+
+// Apply runs every migration in All that hasn't been recorded yet, in
+// Version order, inserting a schema_migrations row as each one succeeds. A
+// migration whose recorded checksum no longer matches its current SQL
+// returns an error rather than silently re-running or ignoring the change.
+func Apply(ctx context.Context, db driver.Conn) error {
+	if err := EnsureMigrationsTable(ctx, db); err != nil {
+		return err
+	}
+
+	done, err := applied(ctx, db)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range All {
+		checksum := m.Checksum()
+		if existing, ok := done[m.Version]; ok {
+			if existing != checksum {
+				return fmt.Errorf("migration %d (%s): checksum mismatch, applied as %s but now %s", m.Version, m.Name, existing, checksum)
+			}
+			continue
+		}
+
+		if err := db.Exec(ctx, m.SQL); err != nil {
+			return fmt.Errorf("applying migration %d (%s): %w", m.Version, m.Name, err)
+		}
+
+		insert := "INSERT INTO ultra_siem.schema_migrations (version, name, checksum, applied_at) VALUES (?, ?, ?, ?)"
+		if err := db.Exec(ctx, insert, uint32(m.Version), m.Name, checksum, time.Now()); err != nil {
+			return fmt.Errorf("recording migration %d (%s): %w", m.Version, m.Name, err)
+		}
+	}
+
+	return nil
+}
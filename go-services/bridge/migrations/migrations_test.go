@@ -0,0 +1,146 @@
+package migrations
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
+)
+
+// fakeMigrationRow is one row of the simulated ultra_siem.schema_migrations
+// table.
+type fakeMigrationRow struct {
+	version  uint32
+	name     string
+	checksum string
+}
+
+// fakeConn is a minimal driver.Conn stand-in that implements just enough of
+// Exec/Query to exercise Apply against an in-memory schema_migrations
+// table; every other method panics so a future change to Apply that starts
+// relying on them fails loudly here instead of silently passing.
+type fakeConn struct {
+	rows    []fakeMigrationRow
+	execLog []string
+}
+
+func (f *fakeConn) Exec(_ context.Context, query string, args ...any) error {
+	f.execLog = append(f.execLog, query)
+	if strings.Contains(query, "INSERT INTO ultra_siem.schema_migrations") {
+		f.rows = append(f.rows, fakeMigrationRow{
+			version:  args[0].(uint32),
+			name:     args[1].(string),
+			checksum: args[2].(string),
+		})
+	}
+	return nil
+}
+
+func (f *fakeConn) Query(context.Context, string, ...any) (driver.Rows, error) {
+	return &fakeRows{rows: f.rows}, nil
+}
+
+func (f *fakeConn) Contributors() []string                        { panic("not implemented") }
+func (f *fakeConn) ServerVersion() (*driver.ServerVersion, error) { panic("not implemented") }
+func (f *fakeConn) Select(context.Context, any, string, ...any) error {
+	panic("not implemented")
+}
+func (f *fakeConn) QueryRow(context.Context, string, ...any) driver.Row { panic("not implemented") }
+func (f *fakeConn) PrepareBatch(context.Context, string, ...driver.PrepareBatchOption) (driver.Batch, error) {
+	panic("not implemented")
+}
+func (f *fakeConn) AsyncInsert(context.Context, string, bool, ...any) error {
+	panic("not implemented")
+}
+func (f *fakeConn) Ping(context.Context) error { panic("not implemented") }
+func (f *fakeConn) Stats() driver.Stats        { panic("not implemented") }
+func (f *fakeConn) Close() error               { return nil }
+
+// fakeRows implements driver.Rows over a fakeConn's in-memory rows.
+type fakeRows struct {
+	rows []fakeMigrationRow
+	idx  int
+}
+
+func (r *fakeRows) Next() bool {
+	if r.idx >= len(r.rows) {
+		return false
+	}
+	r.idx++
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	row := r.rows[r.idx-1]
+	*(dest[0].(*uint32)) = row.version
+	*(dest[1].(*string)) = row.checksum
+	return nil
+}
+
+func (r *fakeRows) ScanStruct(any) error             { panic("not implemented") }
+func (r *fakeRows) ColumnTypes() []driver.ColumnType { panic("not implemented") }
+func (r *fakeRows) Totals(...any) error              { panic("not implemented") }
+func (r *fakeRows) Columns() []string                { panic("not implemented") }
+func (r *fakeRows) Close() error                     { return nil }
+func (r *fakeRows) Err() error                       { return nil }
+func (r *fakeRows) HasData() bool                    { return len(r.rows) > 0 }
+
+// TestApplyRunsEveryMigrationOnAFreshDatabase tests the happy path: with no
+// rows recorded yet, Apply runs every migration in All and records one
+// schema_migrations row per migration.
+func TestApplyRunsEveryMigrationOnAFreshDatabase(t *testing.T) {
+	conn := &fakeConn{}
+
+	if err := Apply(context.Background(), conn); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	if len(conn.rows) != len(All) {
+		t.Fatalf("expected %d recorded migrations, got %d", len(All), len(conn.rows))
+	}
+	for i, m := range All {
+		if conn.rows[i].version != uint32(m.Version) || conn.rows[i].checksum != m.Checksum() {
+			t.Errorf("migration %d: recorded %+v, want version=%d checksum=%s", i, conn.rows[i], m.Version, m.Checksum())
+		}
+	}
+}
+
+// TestApplySkipsAlreadyAppliedMigrations tests that a migration whose
+// recorded checksum still matches its current SQL is skipped rather than
+// re-run.
+func TestApplySkipsAlreadyAppliedMigrations(t *testing.T) {
+	conn := &fakeConn{}
+	for _, m := range All {
+		conn.rows = append(conn.rows, fakeMigrationRow{version: uint32(m.Version), name: m.Name, checksum: m.Checksum()})
+	}
+
+	if err := Apply(context.Background(), conn); err != nil {
+		t.Fatalf("Apply returned error: %v", err)
+	}
+
+	for _, query := range conn.execLog {
+		if strings.Contains(query, "ALTER TABLE") {
+			t.Errorf("expected no migration SQL to run for already-applied migrations, got: %s", query)
+		}
+	}
+}
+
+// TestApplyChecksumMismatchReturnsError tests that a migration recorded
+// with a checksum that no longer matches its SQL (i.e. it was edited after
+// shipping) fails Apply instead of silently re-running or skipping it.
+func TestApplyChecksumMismatchReturnsError(t *testing.T) {
+	conn := &fakeConn{
+		rows: []fakeMigrationRow{
+			{version: uint32(All[0].Version), name: All[0].Name, checksum: "stale-checksum-from-an-edited-migration"},
+		},
+	}
+
+	err := Apply(context.Background(), conn)
+	if err == nil {
+		t.Fatal("expected an error for a checksum mismatch, got nil")
+	}
+	if !strings.Contains(err.Error(), "checksum mismatch") {
+		t.Errorf("expected a checksum mismatch error, got: %v", err)
+	}
+}
@@ -0,0 +1,159 @@
+package parsers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cefHeaderEscapes un-escapes the two sequences CEF allows in its
+// pipe-delimited header fields.
+var cefHeaderEscapes = map[byte]string{'|': "|", '\\': "\\"}
+
+// cefExtensionEscapes un-escapes the sequences CEF allows in extension
+// values: an escaped '=', an escaped backslash, and a literal "\n" for an
+// embedded newline.
+var cefExtensionEscapes = map[byte]string{'=': "=", '\\': "\\", 'n': "\n"}
+
+// ParseCEF decodes one ArcSight CEF message:
+//
+//	CEF:Version|Device Vendor|Device Product|Device Version|Signature ID|Name|Severity|Extension
+//
+// Standard extension keys (src, dst, spt, dpt, suser, proto, rt, msg) are
+// mapped onto Event's fields; every other key (including duser, which has
+// no corresponding field) is kept in Metadata under its raw CEF name.
+func ParseCEF(data []byte) (*Event, error) {
+	line := strings.TrimRight(string(data), "\r\n")
+	if !strings.HasPrefix(line, "CEF:") {
+		return nil, fmt.Errorf("not a CEF message: missing \"CEF:\" prefix")
+	}
+
+	fields, err := splitUnescaped(line, '|', 7)
+	if err != nil {
+		return nil, fmt.Errorf("malformed CEF header: %w", err)
+	}
+	for i := 1; i < 7; i++ {
+		fields[i] = unescape(fields[i], cefHeaderEscapes)
+	}
+	vendor, product, version, sigID, name, severityField, extension := fields[1], fields[2], fields[3], fields[4], fields[5], fields[6], fields[7]
+
+	severity, _ := strconv.Atoi(severityField)
+
+	ev := &Event{
+		RawMessage: line,
+		EventType:  name,
+		Severity:   severity,
+		Metadata: map[string]interface{}{
+			"cef.vendor":       vendor,
+			"cef.product":      product,
+			"cef.version":      version,
+			"cef.signature_id": sigID,
+		},
+	}
+
+	for key, val := range splitCEFExtension(extension) {
+		switch key {
+		case "src":
+			ev.SourceIP = val
+		case "dst":
+			ev.DestinationIP = val
+		case "spt":
+			if p, err := strconv.ParseUint(val, 10, 16); err == nil {
+				ev.SourcePort = uint16(p)
+			}
+		case "dpt":
+			if p, err := strconv.ParseUint(val, 10, 16); err == nil {
+				ev.DestinationPort = uint16(p)
+			}
+		case "suser":
+			ev.User = val
+		case "proto":
+			ev.Protocol = val
+		case "rt":
+			ev.Timestamp = parseCEFTime(val)
+		case "msg":
+			ev.Message = val
+		default:
+			ev.Metadata[key] = val
+		}
+	}
+
+	return ev, nil
+}
+
+// splitUnescaped splits s on the first n unescaped occurrences of sep,
+// returning n+1 fields. A sep preceded by a backslash (which remains in
+// the returned field for the caller to un-escape) doesn't count as a
+// separator.
+func splitUnescaped(s string, sep byte, n int) ([]string, error) {
+	var fields []string
+	var cur strings.Builder
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case escaped:
+			cur.WriteByte(c)
+			escaped = false
+		case c == '\\':
+			cur.WriteByte(c)
+			escaped = true
+		case c == sep && len(fields) < n:
+			fields = append(fields, cur.String())
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	fields = append(fields, cur.String())
+	if len(fields) != n+1 {
+		return nil, fmt.Errorf("expected %d fields separated by %q, got %d", n+1, sep, len(fields))
+	}
+	return fields, nil
+}
+
+// splitCEFExtension parses a CEF extension string ("key1=val1 key2=val two
+// words") into a map, honoring escaped '=' inside values so a value can't
+// be mistaken for the start of the next key.
+func splitCEFExtension(ext string) map[string]string {
+	var eqPositions []int
+	for i := 0; i < len(ext); i++ {
+		if ext[i] == '=' && (i == 0 || ext[i-1] != '\\') {
+			eqPositions = append(eqPositions, i)
+		}
+	}
+
+	result := make(map[string]string, len(eqPositions))
+	for idx, eqPos := range eqPositions {
+		keyStart := strings.LastIndexAny(ext[:eqPos], " \t") + 1
+		key := ext[keyStart:eqPos]
+
+		valEnd := len(ext)
+		if idx+1 < len(eqPositions) {
+			nextEq := eqPositions[idx+1]
+			if sp := strings.LastIndexAny(ext[:nextEq], " \t"); sp >= 0 {
+				valEnd = sp
+			}
+		}
+
+		result[key] = unescape(strings.TrimSpace(ext[eqPos+1:valEnd]), cefExtensionEscapes)
+	}
+	return result
+}
+
+// parseCEFTime parses a CEF "rt" value, which is either epoch
+// milliseconds or one of a handful of common date formats. An
+// unrecognized value yields a zero timestamp rather than an error, since
+// rt is just one of many extension fields.
+func parseCEFTime(val string) int64 {
+	if ms, err := strconv.ParseInt(val, 10, 64); err == nil {
+		return ms / 1000
+	}
+	for _, layout := range []string{time.RFC3339, "Jan 2 2006 15:04:05", "Jan 02 2006 15:04:05"} {
+		if t, err := time.Parse(layout, val); err == nil {
+			return t.Unix()
+		}
+	}
+	return 0
+}
@@ -0,0 +1,26 @@
+package parsers
+
+import "strings"
+
+// unescape replaces each backslash-prefixed byte in s found in escapes
+// with its expansion, leaving any other backslash sequence untouched. CEF
+// and LEEF each escape a different, small set of characters this way.
+func unescape(s string, escapes map[byte]string) string {
+	if !strings.ContainsRune(s, '\\') {
+		return s
+	}
+
+	var b strings.Builder
+	b.Grow(len(s))
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) {
+			if repl, ok := escapes[s[i+1]]; ok {
+				b.WriteString(repl)
+				i++
+				continue
+			}
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
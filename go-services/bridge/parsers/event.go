@@ -0,0 +1,30 @@
+// Package parsers decodes raw SIEM wire formats (syslog RFC5424, ArcSight
+// CEF, QRadar LEEF) into a normalized Event. It has no dependency on the
+// bridge's UltraSIEMEvent (a Go main package can't be imported by a
+// sibling package anyway), the same decoupling the rules package uses for
+// Sigma field binding: the bridge maps an Event onto its own struct and
+// fills in whatever a wire format can't provide (UUID, enrichment, ...).
+package parsers
+
+// Event is the normalized form every format-specific parser produces, a
+// subset of the bridge's UltraSIEMEvent populated from whichever fields
+// the source format actually carries. Anything a parser recognizes but
+// that has no corresponding field is kept in Metadata instead of being
+// dropped.
+type Event struct {
+	Timestamp       int64
+	SourceIP        string
+	DestinationIP   string
+	SourcePort      uint16
+	DestinationPort uint16
+	Protocol        string
+	EventType       string
+	Severity        int
+	User            string
+	Hostname        string
+	Process         string
+	ProcessID       uint32
+	Message         string
+	RawMessage      string
+	Metadata        map[string]interface{}
+}
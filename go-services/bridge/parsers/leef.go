@@ -0,0 +1,109 @@
+package parsers
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// ParseLEEF decodes one QRadar LEEF message, either:
+//
+//	LEEF:1.0|Vendor|Product|Version|EventID|key1=val1<TAB>key2=val2
+//	LEEF:2.0|Vendor|Product|Version|EventID|Delimiter|key1=val1<Delimiter>key2=val2
+//
+// v1 always tab-delimits attributes; v2 names its own delimiter as a
+// sixth header field, either a literal character or a "0xNN"/"xNN" hex
+// escape. Standard attribute keys (src, dst, srcPort, dstPort, usrName,
+// proto, sev, cat) are mapped onto Event's fields; every other key is
+// kept in Metadata under its raw LEEF name.
+func ParseLEEF(data []byte) (*Event, error) {
+	line := strings.TrimRight(string(data), "\r\n")
+	if !strings.HasPrefix(line, "LEEF:") {
+		return nil, fmt.Errorf("not a LEEF message: missing \"LEEF:\" prefix")
+	}
+
+	headerFields := 5
+	isV2 := strings.HasPrefix(line, "LEEF:2.0|")
+	if isV2 {
+		headerFields = 6
+	}
+
+	parts := strings.SplitN(line, "|", headerFields+1)
+	if len(parts) != headerFields+1 {
+		return nil, fmt.Errorf("malformed LEEF header: expected %d fields, got %d", headerFields+1, len(parts))
+	}
+	vendor, product, productVersion, eventID := parts[1], parts[2], parts[3], parts[4]
+
+	delim := byte('\t')
+	if isV2 {
+		delim = leefDelimiter(parts[5])
+	}
+	attributes := parts[headerFields]
+
+	ev := &Event{
+		RawMessage: line,
+		EventType:  eventID,
+		Metadata: map[string]interface{}{
+			"leef.vendor":          vendor,
+			"leef.product":         product,
+			"leef.product_version": productVersion,
+		},
+	}
+
+	for _, attr := range strings.Split(attributes, string(delim)) {
+		key, val, ok := strings.Cut(attr, "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "src":
+			ev.SourceIP = val
+		case "dst":
+			ev.DestinationIP = val
+		case "srcPort":
+			if p, err := strconv.ParseUint(val, 10, 16); err == nil {
+				ev.SourcePort = uint16(p)
+			}
+		case "dstPort":
+			if p, err := strconv.ParseUint(val, 10, 16); err == nil {
+				ev.DestinationPort = uint16(p)
+			}
+		case "usrName":
+			ev.User = val
+		case "proto":
+			ev.Protocol = val
+		case "sev":
+			if s, err := strconv.Atoi(val); err == nil {
+				ev.Severity = s
+			}
+		case "cat":
+			ev.EventType = val
+		default:
+			ev.Metadata[key] = val
+		}
+	}
+
+	return ev, nil
+}
+
+// leefDelimiter resolves a LEEF v2 Delimiter header field to the byte it
+// encodes: a "0xNN"/"xNN" hex escape for an unprintable delimiter (tab is
+// conventionally "0x09"), or the field's single literal character.
+// Anything else falls back to tab, v1's implicit delimiter.
+func leefDelimiter(field string) byte {
+	lower := strings.ToLower(field)
+	if hex, ok := strings.CutPrefix(lower, "0x"); ok {
+		if b, err := strconv.ParseUint(hex, 16, 8); err == nil {
+			return byte(b)
+		}
+	}
+	if hex, ok := strings.CutPrefix(lower, "x"); ok {
+		if b, err := strconv.ParseUint(hex, 16, 8); err == nil {
+			return byte(b)
+		}
+	}
+	if len(field) == 1 {
+		return field[0]
+	}
+	return '\t'
+}
@@ -0,0 +1,82 @@
+package parsers
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"net"
+)
+
+// SyslogListener accepts raw RFC5424 syslog messages over TCP and/or UDP,
+// so an agent can push events directly without going through NATS at all.
+// TCP connections are read one newline-delimited message per line; each
+// UDP datagram is treated as one message.
+type SyslogListener struct {
+	handle func(*Event)
+}
+
+// NewSyslogListener creates a listener that calls handle with each
+// successfully parsed Event. A message that fails to parse is logged and
+// dropped rather than passed to handle.
+func NewSyslogListener(handle func(*Event)) *SyslogListener {
+	return &SyslogListener{handle: handle}
+}
+
+// ListenTCP accepts connections on addr until the listener is closed or
+// Accept fails, spawning one goroutine per connection.
+func (l *SyslogListener) ListenTCP(addr string) error {
+	ln, err := net.Listen("tcp", addr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	defer ln.Close()
+
+	for {
+		conn, err := ln.Accept()
+		if err != nil {
+			return fmt.Errorf("accepting connection on %s: %w", addr, err)
+		}
+		go l.serveTCP(conn)
+	}
+}
+
+func (l *SyslogListener) serveTCP(conn net.Conn) {
+	defer conn.Close()
+	scanner := bufio.NewScanner(conn)
+	for scanner.Scan() {
+		l.parseAndHandle(scanner.Bytes())
+	}
+}
+
+// ListenUDP reads datagrams on addr until ReadFromUDP fails.
+func (l *SyslogListener) ListenUDP(addr string) error {
+	udpAddr, err := net.ResolveUDPAddr("udp", addr)
+	if err != nil {
+		return fmt.Errorf("resolving %s: %w", addr, err)
+	}
+	conn, err := net.ListenUDP("udp", udpAddr)
+	if err != nil {
+		return fmt.Errorf("listening on %s: %w", addr, err)
+	}
+	defer conn.Close()
+
+	buf := make([]byte, 64*1024)
+	for {
+		n, _, err := conn.ReadFromUDP(buf)
+		if err != nil {
+			return fmt.Errorf("reading from %s: %w", addr, err)
+		}
+		data := make([]byte, n)
+		copy(data, buf[:n])
+		l.parseAndHandle(data)
+	}
+}
+
+func (l *SyslogListener) parseAndHandle(data []byte) {
+	event, err := ParseSyslog(data)
+	if err != nil {
+		log.Printf("⚠️ parsers: dropping malformed syslog message: %v", err)
+		return
+	}
+	l.handle(event)
+}
@@ -0,0 +1,144 @@
+package parsers
+
+import "testing"
+
+func TestParseCEF(t *testing.T) {
+	msg := `CEF:0|Security|threatmanager|1.0|100|worm successfully stopped|10|src=10.0.0.1 dst=2.1.2.2 spt=1232 dpt=80 suser=admin duser=victim proto=TCP msg=Detected a \=worm\= attempt`
+
+	ev, err := ParseCEF([]byte(msg))
+	if err != nil {
+		t.Fatalf("ParseCEF failed: %v", err)
+	}
+	if ev.EventType != "worm successfully stopped" {
+		t.Errorf("EventType: got %q", ev.EventType)
+	}
+	if ev.Severity != 10 {
+		t.Errorf("Severity: got %d", ev.Severity)
+	}
+	if ev.SourceIP != "10.0.0.1" || ev.DestinationIP != "2.1.2.2" {
+		t.Errorf("SourceIP/DestinationIP: got %q/%q", ev.SourceIP, ev.DestinationIP)
+	}
+	if ev.SourcePort != 1232 || ev.DestinationPort != 80 {
+		t.Errorf("SourcePort/DestinationPort: got %d/%d", ev.SourcePort, ev.DestinationPort)
+	}
+	if ev.User != "admin" {
+		t.Errorf("User: got %q", ev.User)
+	}
+	if ev.Metadata["duser"] != "victim" {
+		t.Errorf("Metadata[duser]: got %v", ev.Metadata["duser"])
+	}
+	if ev.Message != "Detected a =worm= attempt" {
+		t.Errorf("Message: got %q", ev.Message)
+	}
+}
+
+func TestParseCEFEscapedHeader(t *testing.T) {
+	msg := `CEF:0|Acme|Firewall|2.0|200|Blocked \| connection|5|src=192.168.1.1`
+
+	ev, err := ParseCEF([]byte(msg))
+	if err != nil {
+		t.Fatalf("ParseCEF failed: %v", err)
+	}
+	if ev.EventType != "Blocked | connection" {
+		t.Errorf("EventType: got %q", ev.EventType)
+	}
+}
+
+func TestParseLEEFv1(t *testing.T) {
+	msg := "LEEF:1.0|Acme|FW|1.0|Deny|src=192.168.1.1\tdst=192.168.1.2\tsrcPort=5000\tdstPort=443\tproto=TCP\tusrName=bob\tsev=7"
+
+	ev, err := ParseLEEF([]byte(msg))
+	if err != nil {
+		t.Fatalf("ParseLEEF failed: %v", err)
+	}
+	if ev.SourceIP != "192.168.1.1" || ev.DestinationIP != "192.168.1.2" {
+		t.Errorf("SourceIP/DestinationIP: got %q/%q", ev.SourceIP, ev.DestinationIP)
+	}
+	if ev.SourcePort != 5000 || ev.DestinationPort != 443 {
+		t.Errorf("SourcePort/DestinationPort: got %d/%d", ev.SourcePort, ev.DestinationPort)
+	}
+	if ev.User != "bob" {
+		t.Errorf("User: got %q", ev.User)
+	}
+	if ev.Severity != 7 {
+		t.Errorf("Severity: got %d", ev.Severity)
+	}
+}
+
+func TestParseLEEFv2CustomDelimiter(t *testing.T) {
+	msg := "LEEF:2.0|Acme|FW|1.0|Allow|^|src=10.1.1.1^usrName=alice^cat=AuthSuccess"
+
+	ev, err := ParseLEEF([]byte(msg))
+	if err != nil {
+		t.Fatalf("ParseLEEF failed: %v", err)
+	}
+	if ev.SourceIP != "10.1.1.1" {
+		t.Errorf("SourceIP: got %q", ev.SourceIP)
+	}
+	if ev.User != "alice" {
+		t.Errorf("User: got %q", ev.User)
+	}
+	if ev.EventType != "AuthSuccess" {
+		t.Errorf("EventType: got %q", ev.EventType)
+	}
+}
+
+func TestParseSyslog(t *testing.T) {
+	msg := `<34>1 2025-03-01T12:00:00.000Z myhost su 1234 ID47 [exampleSDID@32473 iut="3" eventSource="App"] su root failed`
+
+	ev, err := ParseSyslog([]byte(msg))
+	if err != nil {
+		t.Fatalf("ParseSyslog failed: %v", err)
+	}
+	if ev.Severity != 2 { // 34 mod 8 == 2
+		t.Errorf("Severity: got %d", ev.Severity)
+	}
+	if ev.Hostname != "myhost" {
+		t.Errorf("Hostname: got %q", ev.Hostname)
+	}
+	if ev.Process != "su" {
+		t.Errorf("Process: got %q", ev.Process)
+	}
+	if ev.ProcessID != 1234 {
+		t.Errorf("ProcessID: got %d", ev.ProcessID)
+	}
+	if ev.Message != "su root failed" {
+		t.Errorf("Message: got %q", ev.Message)
+	}
+	if ev.Metadata["exampleSDID@32473.iut"] != "3" {
+		t.Errorf("Metadata[exampleSDID@32473.iut]: got %v", ev.Metadata["exampleSDID@32473.iut"])
+	}
+}
+
+func TestParseSyslogNoStructuredData(t *testing.T) {
+	msg := `<13>1 2025-03-01T12:00:00Z - - - - - login failed`
+
+	ev, err := ParseSyslog([]byte(msg))
+	if err != nil {
+		t.Fatalf("ParseSyslog failed: %v", err)
+	}
+	if ev.Hostname != "" || ev.Process != "" {
+		t.Errorf("expected nil Hostname/Process, got %q/%q", ev.Hostname, ev.Process)
+	}
+	if ev.Message != "login failed" {
+		t.Errorf("Message: got %q", ev.Message)
+	}
+}
+
+func TestForSubject(t *testing.T) {
+	if _, ok := ForSubject("ultra_siem.raw.cef"); !ok {
+		t.Error("expected a parser for ultra_siem.raw.cef")
+	}
+	if _, ok := ForSubject("ultra_siem.events"); ok {
+		t.Error("expected no parser for ultra_siem.events")
+	}
+}
+
+func TestForContentType(t *testing.T) {
+	if _, ok := ForContentType("application/leef"); !ok {
+		t.Error("expected a parser for application/leef")
+	}
+	if _, ok := ForContentType("application/json"); ok {
+		t.Error("expected no parser for application/json")
+	}
+}
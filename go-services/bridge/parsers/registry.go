@@ -0,0 +1,39 @@
+package parsers
+
+import "strings"
+
+// Parser decodes one raw message body into a normalized Event.
+type Parser func(data []byte) (*Event, error)
+
+// byFormatSuffix maps a NATS subject's trailing segment (e.g.
+// "ultra_siem.raw.cef" -> "cef") and a Content-Type header's subtype
+// (e.g. "application/cef" -> "cef") to the parser that decodes it.
+var byFormatSuffix = map[string]Parser{
+	"syslog": ParseSyslog,
+	"cef":    ParseCEF,
+	"leef":   ParseLEEF,
+}
+
+// ForSubject returns the parser registered for subject's trailing segment
+// (the part after the last '.'), e.g. "ultra_siem.raw.cef" selects the
+// CEF parser.
+func ForSubject(subject string) (Parser, bool) {
+	idx := strings.LastIndexByte(subject, '.')
+	if idx < 0 {
+		return nil, false
+	}
+	parser, ok := byFormatSuffix[subject[idx+1:]]
+	return parser, ok
+}
+
+// ForContentType returns the parser registered for a NATS message's
+// Content-Type header value (e.g. "application/cef"), for producers that
+// tag format via header instead of subject.
+func ForContentType(contentType string) (Parser, bool) {
+	idx := strings.LastIndexByte(contentType, '/')
+	if idx < 0 {
+		return nil, false
+	}
+	parser, ok := byFormatSuffix[contentType[idx+1:]]
+	return parser, ok
+}
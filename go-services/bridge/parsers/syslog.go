@@ -0,0 +1,122 @@
+package parsers
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// syslogHeaderRe matches RFC5424's fixed header:
+// "<PRI>VERSION TIMESTAMP HOSTNAME APP-NAME PROCID MSGID ". Any of
+// TIMESTAMP/HOSTNAME/APP-NAME/PROCID/MSGID may be the nil value "-".
+var syslogHeaderRe = regexp.MustCompile(`^<(\d+)>(\d+) (\S+) (\S+) (\S+) (\S+) (\S+) `)
+
+// structuredDataElementRe matches one "[SD-ID param="val" ...]" element.
+var structuredDataElementRe = regexp.MustCompile(`^\[([^\s\]=]+)((?:\s+[^\s=\]]+="(?:[^"\\]|\\.)*")*)\]`)
+
+// structuredDataParamRe matches one param="val" pair within an element.
+var structuredDataParamRe = regexp.MustCompile(`([^\s=\]]+)="((?:[^"\\]|\\.)*)"`)
+
+var syslogSDParamEscapes = map[byte]string{'"': "\"", '\\': "\\", ']': "]"}
+
+// ParseSyslog decodes one RFC5424 syslog message. PRI's severity (PRI mod
+// 8) becomes Event.Severity; HOSTNAME/APP-NAME/PROCID map onto
+// Hostname/Process/ProcessID; structured-data params are flattened into
+// Metadata as "SD-ID.param"; everything after structured data is MSG.
+func ParseSyslog(data []byte) (*Event, error) {
+	line := strings.TrimRight(string(data), "\r\n")
+
+	m := syslogHeaderRe.FindStringSubmatch(line)
+	if m == nil {
+		return nil, fmt.Errorf("malformed RFC5424 syslog header")
+	}
+
+	pri, err := strconv.Atoi(m[1])
+	if err != nil {
+		return nil, fmt.Errorf("parsing PRI: %w", err)
+	}
+	severity := pri % 8
+	facility := pri / 8
+
+	var procID uint32
+	if raw := nilableField(m[6]); raw != "" {
+		if p, err := strconv.ParseUint(raw, 10, 32); err == nil {
+			procID = uint32(p)
+		}
+	}
+
+	rest := line[len(m[0]):]
+	structuredData, msg, err := splitStructuredData(rest)
+	if err != nil {
+		return nil, err
+	}
+
+	metadata := map[string]interface{}{"syslog.facility": facility}
+	for sdID, params := range structuredData {
+		for k, v := range params {
+			metadata[sdID+"."+k] = v
+		}
+	}
+
+	return &Event{
+		Timestamp:  parseSyslogTimestamp(m[3]),
+		Hostname:   nilableField(m[4]),
+		Process:    nilableField(m[5]),
+		ProcessID:  procID,
+		Severity:   severity,
+		EventType:  "syslog",
+		Message:    msg,
+		RawMessage: line,
+		Metadata:   metadata,
+	}, nil
+}
+
+func nilableField(s string) string {
+	if s == "-" {
+		return ""
+	}
+	return s
+}
+
+// parseSyslogTimestamp parses RFC5424's RFC3339-with-optional-fractional-
+// seconds TIMESTAMP field. A nil "-" or unparseable value yields 0 rather
+// than an error, since MSG is still usable without it.
+func parseSyslogTimestamp(s string) int64 {
+	if s == "-" {
+		return 0
+	}
+	if t, err := time.Parse(time.RFC3339Nano, s); err == nil {
+		return t.Unix()
+	}
+	return 0
+}
+
+// splitStructuredData parses the STRUCTURED-DATA portion of an RFC5424
+// message (either "-" or one or more bracketed SD elements) off the front
+// of rest, returning the parsed elements and the remaining MSG text.
+func splitStructuredData(rest string) (map[string]map[string]string, string, error) {
+	rest = strings.TrimPrefix(rest, " ")
+	if strings.HasPrefix(rest, "-") {
+		return nil, strings.TrimPrefix(rest[1:], " "), nil
+	}
+
+	sd := make(map[string]map[string]string)
+	for len(rest) > 0 && rest[0] == '[' {
+		m := structuredDataElementRe.FindStringSubmatchIndex(rest)
+		if m == nil {
+			return nil, "", fmt.Errorf("malformed structured data at %q", rest)
+		}
+
+		sdID := rest[m[2]:m[3]]
+		params := make(map[string]string)
+		for _, pm := range structuredDataParamRe.FindAllStringSubmatch(rest[m[4]:m[5]], -1) {
+			params[pm[1]] = unescape(pm[2], syslogSDParamEscapes)
+		}
+		sd[sdID] = params
+
+		rest = rest[m[1]:]
+	}
+	return sd, strings.TrimPrefix(rest, " "), nil
+}
@@ -0,0 +1,126 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/nats-io/nats.go"
+
+	"github.com/YASSER-MN/ultra-siem/go-services/bridge/parsers"
+)
+
+// rawIngestSubjects are the NATS subjects carrying non-JSON wire formats,
+// one table batcher each, all writing into ultra_siem.events alongside
+// buildEventsRow's JSON UltraSIEMEvent intake.
+var rawIngestSubjects = []string{
+	"ultra_siem.raw.syslog",
+	"ultra_siem.raw.cef",
+	"ultra_siem.raw.leef",
+}
+
+// startRawIngestBatchers starts one table batcher per entry in
+// rawIngestSubjects, each decoding with the parser selected by its
+// subject (buildRawEventRow falls back to a Content-Type header if the
+// subject itself isn't recognized).
+func (b *SimpleBridge) startRawIngestBatchers() error {
+	for _, subject := range rawIngestSubjects {
+		durable := b.config.ConsumerDurablePrefix + "-raw-" + strings.TrimPrefix(subject, "ultra_siem.raw.")
+		tb, err := b.startTableBatcher("events", subject, durable, "INSERT INTO ultra_siem.events", b.buildRawEventRow)
+		if err != nil {
+			return fmt.Errorf("starting raw ingest batcher for %s: %w", subject, err)
+		}
+		b.batchers = append(b.batchers, tb)
+	}
+	return nil
+}
+
+// buildRawEventRow decodes msg using the parser selected by its subject
+// or, failing that, its Content-Type header, converts the result into an
+// UltraSIEMEvent, and runs it through the same enrichment/Sigma/column
+// pipeline as a JSON ultra_siem.events message.
+func (b *SimpleBridge) buildRawEventRow(msg *nats.Msg) (*rowResult, error) {
+	parser, ok := parsers.ForSubject(msg.Subject)
+	if !ok {
+		parser, ok = parsers.ForContentType(msg.Header.Get("Content-Type"))
+	}
+	if !ok {
+		return nil, fmt.Errorf("no parser registered for subject %q", msg.Subject)
+	}
+
+	parsed, err := parser(msg.Data)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s message: %w", msg.Subject, err)
+	}
+
+	ctx := extractTraceContext(context.Background(), msg)
+	ctx, span := b.tracer.Start(ctx, "buildRawEventRow")
+	defer span.End()
+
+	event := eventFromParsed(parsed)
+	return b.buildEventsRowFromEvent(ctx, &event)
+}
+
+// eventFromParsed maps a parsers.Event onto an UltraSIEMEvent, leaving
+// every field the wire format didn't populate at its zero value; the
+// shared enrichment pipeline (enrichUltraSIEMEvent) fills in IDs,
+// defaults, and compliance tags the same way it does for a JSON event.
+func eventFromParsed(p *parsers.Event) UltraSIEMEvent {
+	return UltraSIEMEvent{
+		Timestamp:       p.Timestamp,
+		SourceIP:        p.SourceIP,
+		DestinationIP:   p.DestinationIP,
+		SourcePort:      p.SourcePort,
+		DestinationPort: p.DestinationPort,
+		Protocol:        p.Protocol,
+		EventType:       p.EventType,
+		Severity:        p.Severity,
+		User:            p.User,
+		Hostname:        p.Hostname,
+		Process:         p.Process,
+		ProcessID:       p.ProcessID,
+		Message:         p.Message,
+		RawMessage:      p.RawMessage,
+		Metadata:        p.Metadata,
+	}
+}
+
+// startSyslogListener starts the raw TCP/UDP syslog listener configured by
+// BridgeConfig.SyslogTCPListenAddr/SyslogUDPListenAddr, if either is set.
+// Each parsed message is converted to an UltraSIEMEvent and published to
+// ultra_siem.events, so it flows through the normal batching/retry/DLQ
+// pipeline instead of bypassing it.
+func (b *SimpleBridge) startSyslogListener() {
+	if b.config.SyslogTCPListenAddr == "" && b.config.SyslogUDPListenAddr == "" {
+		return
+	}
+
+	listener := parsers.NewSyslogListener(func(parsed *parsers.Event) {
+		event := eventFromParsed(parsed)
+		data, err := json.Marshal(event)
+		if err != nil {
+			log.Printf("⚠️ syslog listener: failed to marshal event: %v", err)
+			return
+		}
+		if _, err := b.js.Publish("ultra_siem.events", data); err != nil {
+			log.Printf("⚠️ syslog listener: failed to publish to ultra_siem.events: %v", err)
+		}
+	})
+
+	if addr := b.config.SyslogTCPListenAddr; addr != "" {
+		go func() {
+			if err := listener.ListenTCP(addr); err != nil {
+				log.Printf("⚠️ syslog TCP listener on %s stopped: %v", addr, err)
+			}
+		}()
+	}
+	if addr := b.config.SyslogUDPListenAddr; addr != "" {
+		go func() {
+			if err := listener.ListenUDP(addr); err != nil {
+				log.Printf("⚠️ syslog UDP listener on %s stopped: %v", addr, err)
+			}
+		}()
+	}
+}
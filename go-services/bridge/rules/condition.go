@@ -0,0 +1,221 @@
+// Package rules implements a Sigma-format detection engine: it parses Sigma
+// YAML rules into a predicate tree and evaluates that tree against the
+// generic field map an event reduces to, so the bridge can flag events that
+// the static GeoIP/threat-intel enrichment never would.
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Condition is one node of a compiled rule's predicate tree. Eval never
+// errors: a field missing from fields, or a type it can't compare, just
+// evaluates to false rather than failing the whole rule.
+type Condition interface {
+	Eval(fields map[string]interface{}) bool
+}
+
+// fieldOp is a Sigma field modifier (the part after "|" in "Field|contains").
+type fieldOp string
+
+const (
+	opEquals     fieldOp = "equals"
+	opContains   fieldOp = "contains"
+	opStartsWith fieldOp = "startswith"
+	opEndsWith   fieldOp = "endswith"
+	opRegex      fieldOp = "re"
+	opGT         fieldOp = "gt"
+	opGTE        fieldOp = "gte"
+	opLT         fieldOp = "lt"
+	opLTE        fieldOp = "lte"
+)
+
+// fieldMatch tests one field against one value with one operator. A
+// selection like "CommandLine|contains: [IEX, DownloadString]" compiles to
+// an orNode of two fieldMatches, since Sigma ORs a list of values together.
+type fieldMatch struct {
+	field string
+	op    fieldOp
+	value interface{}
+	re    *regexp.Regexp // compiled by newFieldMatch for opRegex
+}
+
+func newFieldMatch(field string, op fieldOp, value interface{}) (*fieldMatch, error) {
+	fm := &fieldMatch{field: field, op: op, value: value}
+	if op == opRegex {
+		pattern, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("field %q: re modifier requires a string pattern", field)
+		}
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("field %q: invalid regex %q: %w", field, pattern, err)
+		}
+		fm.re = re
+	}
+	return fm, nil
+}
+
+func (f *fieldMatch) Eval(fields map[string]interface{}) bool {
+	actual, ok := fields[f.field]
+	if !ok {
+		return false
+	}
+
+	switch f.op {
+	case opContains, opStartsWith, opEndsWith, opRegex:
+		actualStr, ok := toString(actual)
+		if !ok {
+			return false
+		}
+		wantStr, ok := toString(f.value)
+		if !ok {
+			return false
+		}
+		switch f.op {
+		case opContains:
+			return strings.Contains(actualStr, wantStr)
+		case opStartsWith:
+			return strings.HasPrefix(actualStr, wantStr)
+		case opEndsWith:
+			return strings.HasSuffix(actualStr, wantStr)
+		case opRegex:
+			return f.re.MatchString(actualStr)
+		}
+	case opGT, opGTE, opLT, opLTE:
+		actualNum, ok := toFloat(actual)
+		if !ok {
+			return false
+		}
+		wantNum, ok := toFloat(f.value)
+		if !ok {
+			return false
+		}
+		switch f.op {
+		case opGT:
+			return actualNum > wantNum
+		case opGTE:
+			return actualNum >= wantNum
+		case opLT:
+			return actualNum < wantNum
+		case opLTE:
+			return actualNum <= wantNum
+		}
+	case opEquals:
+		return equalsValue(actual, f.value)
+	}
+	return false
+}
+
+func toString(v interface{}) (string, bool) {
+	switch t := v.(type) {
+	case string:
+		return t, true
+	case fmt.Stringer:
+		return t.String(), true
+	default:
+		return "", false
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch t := v.(type) {
+	case float64:
+		return t, true
+	case float32:
+		return float64(t), true
+	case int:
+		return float64(t), true
+	case int32:
+		return float64(t), true
+	case int64:
+		return float64(t), true
+	case uint16:
+		return float64(t), true
+	case uint32:
+		return float64(t), true
+	case uint64:
+		return float64(t), true
+	case string:
+		f, err := strconv.ParseFloat(t, 64)
+		if err != nil {
+			return 0, false
+		}
+		return f, true
+	default:
+		return 0, false
+	}
+}
+
+func equalsValue(actual, want interface{}) bool {
+	if actualStr, ok := toString(actual); ok {
+		if wantStr, ok := toString(want); ok {
+			return actualStr == wantStr
+		}
+	}
+	if actualNum, ok := toFloat(actual); ok {
+		if wantNum, ok := toFloat(want); ok {
+			return actualNum == wantNum
+		}
+	}
+	return false
+}
+
+// andNode matches when every child matches (Sigma's implicit AND across
+// fields within one selection, and explicit "and" in a condition string).
+type andNode struct{ children []Condition }
+
+func (n *andNode) Eval(fields map[string]interface{}) bool {
+	for _, c := range n.children {
+		if !c.Eval(fields) {
+			return false
+		}
+	}
+	return true
+}
+
+// orNode matches when any child matches (a selection field's value list, or
+// explicit "or" in a condition string).
+type orNode struct{ children []Condition }
+
+func (n *orNode) Eval(fields map[string]interface{}) bool {
+	for _, c := range n.children {
+		if c.Eval(fields) {
+			return true
+		}
+	}
+	return false
+}
+
+// notNode inverts its child ("not" in a condition string).
+type notNode struct{ child Condition }
+
+func (n *notNode) Eval(fields map[string]interface{}) bool {
+	return !n.child.Eval(fields)
+}
+
+// quantifierNode implements "1 of x*"/"all of x*": at least one (or every)
+// matching named selection must match.
+type quantifierNode struct {
+	all        bool
+	selections []Condition
+}
+
+func (n *quantifierNode) Eval(fields map[string]interface{}) bool {
+	if len(n.selections) == 0 {
+		return false
+	}
+	for _, s := range n.selections {
+		matched := s.Eval(fields)
+		if n.all && !matched {
+			return false
+		}
+		if !n.all && matched {
+			return true
+		}
+	}
+	return n.all
+}
@@ -0,0 +1,167 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// conditionParser is a small recursive-descent parser for Sigma's
+// condition strings ("selection1 and not selection2", "1 of selection*",
+// "all of them"). Precedence, loosest to tightest: or, and, not.
+type conditionParser struct {
+	tokens     []string
+	pos        int
+	selections map[string]Condition
+}
+
+func parseCondition(expr string, selections map[string]Condition) (Condition, error) {
+	p := &conditionParser{tokens: tokenizeCondition(expr), selections: selections}
+	cond, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if p.pos != len(p.tokens) {
+		return nil, fmt.Errorf("unexpected token %q in condition %q", p.peek(), expr)
+	}
+	return cond, nil
+}
+
+func tokenizeCondition(expr string) []string {
+	expr = strings.ReplaceAll(expr, "(", " ( ")
+	expr = strings.ReplaceAll(expr, ")", " ) ")
+	return strings.Fields(expr)
+}
+
+func (p *conditionParser) peek() string {
+	if p.pos >= len(p.tokens) {
+		return ""
+	}
+	return p.tokens[p.pos]
+}
+
+func (p *conditionParser) next() string {
+	tok := p.peek()
+	p.pos++
+	return tok
+}
+
+func (p *conditionParser) parseOr() (Condition, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	children := []Condition{left}
+	for strings.EqualFold(p.peek(), "or") {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &orNode{children: children}, nil
+}
+
+func (p *conditionParser) parseAnd() (Condition, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	children := []Condition{left}
+	for strings.EqualFold(p.peek(), "and") {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, right)
+	}
+	if len(children) == 1 {
+		return children[0], nil
+	}
+	return &andNode{children: children}, nil
+}
+
+func (p *conditionParser) parseNot() (Condition, error) {
+	if strings.EqualFold(p.peek(), "not") {
+		p.next()
+		child, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{child: child}, nil
+	}
+	return p.parseAtom()
+}
+
+func (p *conditionParser) parseAtom() (Condition, error) {
+	tok := p.peek()
+	if tok == "" {
+		return nil, fmt.Errorf("unexpected end of condition")
+	}
+
+	if tok == "(" {
+		p.next()
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if p.peek() != ")" {
+			return nil, fmt.Errorf("expected ')', got %q", p.peek())
+		}
+		p.next()
+		return inner, nil
+	}
+
+	if tok == "1" || strings.EqualFold(tok, "all") {
+		return p.parseQuantifier()
+	}
+
+	p.next()
+	return p.resolveSelection(tok)
+}
+
+// parseQuantifier handles "1 of x*" / "all of x*" / "all of them".
+func (p *conditionParser) parseQuantifier() (Condition, error) {
+	all := strings.EqualFold(p.next(), "all")
+
+	if !strings.EqualFold(p.peek(), "of") {
+		return nil, fmt.Errorf("expected 'of', got %q", p.peek())
+	}
+	p.next()
+
+	pattern := p.next()
+	if pattern == "" {
+		return nil, fmt.Errorf("expected selection pattern after 'of'")
+	}
+
+	var matched []Condition
+	if strings.EqualFold(pattern, "them") {
+		for _, cond := range p.selections {
+			matched = append(matched, cond)
+		}
+	} else {
+		prefix := strings.TrimSuffix(pattern, "*")
+		for name, cond := range p.selections {
+			if pattern == name || (strings.HasSuffix(pattern, "*") && strings.HasPrefix(name, prefix)) {
+				matched = append(matched, cond)
+			}
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("no selections match pattern %q", pattern)
+	}
+
+	return &quantifierNode{all: all, selections: matched}, nil
+}
+
+func (p *conditionParser) resolveSelection(name string) (Condition, error) {
+	cond, ok := p.selections[name]
+	if !ok {
+		return nil, fmt.Errorf("condition references unknown selection %q", name)
+	}
+	return cond, nil
+}
@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"sync"
+)
+
+// Match is one rule firing against a single event.
+type Match struct {
+	RuleID string
+	Title  string
+	Level  string
+}
+
+// Engine holds the currently-loaded rule set and evaluates it against
+// events. Rules are swapped atomically by Reload, so a hot-reload never
+// evaluates a half-updated set.
+type Engine struct {
+	mu    sync.RWMutex
+	rules []*Rule
+}
+
+// NewEngine builds an Engine over an initial rule set.
+func NewEngine(rules []*Rule) *Engine {
+	return &Engine{rules: rules}
+}
+
+// Reload atomically replaces the engine's rule set.
+func (e *Engine) Reload(rules []*Rule) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.rules = rules
+}
+
+// RuleCount returns how many rules are currently loaded.
+func (e *Engine) RuleCount() int {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	return len(e.rules)
+}
+
+// Evaluate runs every loaded rule against fields concurrently and returns a
+// Match for each one that fired. Rule order in the result is unspecified.
+func (e *Engine) Evaluate(fields map[string]interface{}) []Match {
+	e.mu.RLock()
+	rules := e.rules
+	e.mu.RUnlock()
+
+	if len(rules) == 0 {
+		return nil
+	}
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	var matches []Match
+
+	for _, rule := range rules {
+		wg.Add(1)
+		go func(r *Rule) {
+			defer wg.Done()
+			if !r.Eval(fields) {
+				return
+			}
+			mu.Lock()
+			matches = append(matches, Match{RuleID: r.ID, Title: r.Title, Level: r.Level})
+			mu.Unlock()
+		}(rule)
+	}
+	wg.Wait()
+
+	return matches
+}
@@ -0,0 +1,30 @@
+package rules
+
+import (
+	"reflect"
+	"strings"
+)
+
+// FieldsFromStruct reflects over v (a struct, not a pointer) and returns its
+// field values keyed by both their Go field name and their json tag (sans
+// ",omitempty"), so a Sigma rule can reference a field either way — e.g.
+// "CommandLine" or "command_line" both resolve to the same value. This is
+// the "configurable schema" binding detection rules to whatever struct the
+// caller passes; the engine itself has no notion of UltraSIEMEvent.
+func FieldsFromStruct(v interface{}) map[string]interface{} {
+	val := reflect.ValueOf(v)
+	typ := val.Type()
+
+	out := make(map[string]interface{}, typ.NumField()*2)
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		fv := val.Field(i).Interface()
+
+		out[f.Name] = fv
+
+		if tag := strings.Split(f.Tag.Get("json"), ",")[0]; tag != "" && tag != "-" {
+			out[tag] = fv
+		}
+	}
+	return out
+}
@@ -0,0 +1,89 @@
+package rules
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// LoadDir parses every *.yml/*.yaml file in dir into a Rule. A file that
+// fails to parse is logged and skipped rather than failing the whole load,
+// the same way a bad GeoIP/threat-intel provider doesn't stop the others
+// from loading.
+func LoadDir(dir string) ([]*Rule, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("reading rules directory %s: %w", dir, err)
+	}
+
+	var rules []*Rule
+	for _, entry := range entries {
+		if entry.IsDir() || !isRuleFile(entry.Name()) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			log.Printf("⚠️ rules: reading %s: %v", path, err)
+			continue
+		}
+		rule, err := ParseRule(data)
+		if err != nil {
+			log.Printf("⚠️ rules: parsing %s: %v", path, err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+func isRuleFile(name string) bool {
+	ext := strings.ToLower(filepath.Ext(name))
+	return ext == ".yml" || ext == ".yaml"
+}
+
+// Watch reloads engine from dir whenever a rule file under it changes, and
+// blocks until stop is closed. A reload that fails to read the directory
+// leaves the engine's current rule set in place and just logs the error,
+// so a transient filesystem hiccup never drops detection coverage to zero.
+func Watch(dir string, engine *Engine, stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("creating rules watcher: %w", err)
+	}
+	defer watcher.Close()
+
+	if err := watcher.Add(dir); err != nil {
+		return fmt.Errorf("watching rules directory %s: %w", dir, err)
+	}
+
+	for {
+		select {
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if !isRuleFile(event.Name) {
+				continue
+			}
+			rules, err := LoadDir(dir)
+			if err != nil {
+				log.Printf("⚠️ rules: reload of %s failed: %v", dir, err)
+				continue
+			}
+			engine.Reload(rules)
+			log.Printf("📋 rules: reloaded %d rule(s) from %s", len(rules), dir)
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			log.Printf("⚠️ rules: watcher error on %s: %v", dir, err)
+		case <-stop:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,78 @@
+package rules
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Rule is one compiled Sigma rule, ready to evaluate against an event's
+// field map.
+type Rule struct {
+	ID        string
+	Title     string
+	Level     string
+	LogSource map[string]string
+	condition Condition
+}
+
+// Eval reports whether the rule's compiled condition matches fields.
+func (r *Rule) Eval(fields map[string]interface{}) bool {
+	return r.condition.Eval(fields)
+}
+
+// sigmaDoc mirrors the subset of the Sigma rule format this package
+// understands: title/id/level metadata, an optional logsource filter, and a
+// detection block of named selections plus a condition string.
+type sigmaDoc struct {
+	Title     string                 `yaml:"title"`
+	ID        string                 `yaml:"id"`
+	Level     string                 `yaml:"level"`
+	LogSource map[string]string      `yaml:"logsource"`
+	Detection map[string]interface{} `yaml:"detection"`
+}
+
+// ParseRule compiles one Sigma YAML document into a Rule.
+func ParseRule(data []byte) (*Rule, error) {
+	var doc sigmaDoc
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("parsing sigma rule: %w", err)
+	}
+	if doc.Title == "" {
+		return nil, fmt.Errorf("sigma rule missing title")
+	}
+
+	conditionExpr, ok := doc.Detection["condition"].(string)
+	if !ok || conditionExpr == "" {
+		return nil, fmt.Errorf("rule %q: detection.condition must be a string", doc.Title)
+	}
+
+	selections := make(map[string]Condition, len(doc.Detection)-1)
+	for name, raw := range doc.Detection {
+		if name == "condition" {
+			continue
+		}
+		block, ok := raw.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("rule %q: selection %q must be a mapping", doc.Title, name)
+		}
+		cond, err := compileSelection(block)
+		if err != nil {
+			return nil, fmt.Errorf("rule %q: selection %q: %w", doc.Title, name, err)
+		}
+		selections[name] = cond
+	}
+
+	condition, err := parseCondition(conditionExpr, selections)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", doc.Title, err)
+	}
+
+	return &Rule{
+		ID:        doc.ID,
+		Title:     doc.Title,
+		Level:     doc.Level,
+		LogSource: doc.LogSource,
+		condition: condition,
+	}, nil
+}
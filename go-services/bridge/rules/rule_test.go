@@ -0,0 +1,140 @@
+package rules
+
+import "testing"
+
+const samplePowerShellRule = `
+title: Suspicious PowerShell Download
+id: 11111111-1111-1111-1111-111111111111
+level: high
+logsource:
+  category: process_creation
+detection:
+  selection_cmd:
+    CommandLine|contains:
+      - 'DownloadString'
+      - 'IEX'
+  selection_proc:
+    Process|endswith: 'powershell.exe'
+  filter_admin:
+    User: 'admin'
+  condition: selection_cmd and selection_proc and not filter_admin
+`
+
+func TestParseRuleAndEval(t *testing.T) {
+	rule, err := ParseRule([]byte(samplePowerShellRule))
+	if err != nil {
+		t.Fatalf("ParseRule failed: %v", err)
+	}
+	if rule.Title != "Suspicious PowerShell Download" {
+		t.Errorf("Title: got %q", rule.Title)
+	}
+	if rule.Level != "high" {
+		t.Errorf("Level: got %q", rule.Level)
+	}
+
+	tests := []struct {
+		name   string
+		fields map[string]interface{}
+		want   bool
+	}{
+		{
+			name: "matches non-admin download",
+			fields: map[string]interface{}{
+				"CommandLine": "powershell.exe -c IEX(New-Object Net.WebClient).DownloadString(...)",
+				"Process":     "C:\\Windows\\System32\\powershell.exe",
+				"User":        "victim",
+			},
+			want: true,
+		},
+		{
+			name: "filtered out for admin",
+			fields: map[string]interface{}{
+				"CommandLine": "powershell.exe -c IEX(...)",
+				"Process":     "powershell.exe",
+				"User":        "admin",
+			},
+			want: false,
+		},
+		{
+			name: "no command line match",
+			fields: map[string]interface{}{
+				"CommandLine": "powershell.exe -c Get-Process",
+				"Process":     "powershell.exe",
+				"User":        "victim",
+			},
+			want: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := rule.Eval(tt.fields); got != tt.want {
+				t.Errorf("Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseRuleQuantifier(t *testing.T) {
+	doc := `
+title: Any Suspicious Indicator
+detection:
+  selection_a:
+    FieldA: 'bad'
+  selection_b:
+    FieldB: 'worse'
+  condition: 1 of selection_*
+`
+	rule, err := ParseRule([]byte(doc))
+	if err != nil {
+		t.Fatalf("ParseRule failed: %v", err)
+	}
+
+	if !rule.Eval(map[string]interface{}{"FieldA": "bad", "FieldB": "fine"}) {
+		t.Error("expected match on FieldA alone")
+	}
+	if rule.Eval(map[string]interface{}{"FieldA": "fine", "FieldB": "fine"}) {
+		t.Error("expected no match when neither selection fires")
+	}
+}
+
+func TestParseRuleMissingCondition(t *testing.T) {
+	doc := `
+title: Broken Rule
+detection:
+  selection_a:
+    FieldA: 'bad'
+`
+	if _, err := ParseRule([]byte(doc)); err == nil {
+		t.Error("expected error for missing condition")
+	}
+}
+
+func TestEngineEvaluate(t *testing.T) {
+	rule, err := ParseRule([]byte(samplePowerShellRule))
+	if err != nil {
+		t.Fatalf("ParseRule failed: %v", err)
+	}
+
+	engine := NewEngine([]*Rule{rule})
+	if engine.RuleCount() != 1 {
+		t.Fatalf("RuleCount() = %d, want 1", engine.RuleCount())
+	}
+
+	matches := engine.Evaluate(map[string]interface{}{
+		"CommandLine": "powershell.exe IEX DownloadString",
+		"Process":     "powershell.exe",
+		"User":        "victim",
+	})
+	if len(matches) != 1 {
+		t.Fatalf("Evaluate() returned %d matches, want 1", len(matches))
+	}
+	if matches[0].Title != rule.Title {
+		t.Errorf("match title: got %q, want %q", matches[0].Title, rule.Title)
+	}
+
+	engine.Reload(nil)
+	if engine.RuleCount() != 0 {
+		t.Errorf("RuleCount() after Reload(nil) = %d, want 0", engine.RuleCount())
+	}
+}
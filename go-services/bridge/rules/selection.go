@@ -0,0 +1,68 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// compileSelection turns one Sigma selection block into a Condition. A
+// selection is a map of "field" or "field|modifier" to either a scalar
+// value, or a list of values (which Sigma ORs together); fields within the
+// same selection are ANDed. This covers the common single-map selection
+// shape; a selection given as a list of maps (OR of AND-blocks) is not
+// supported.
+func compileSelection(raw map[string]interface{}) (Condition, error) {
+	var fieldConds []Condition
+
+	for key, value := range raw {
+		field, op, err := parseFieldKey(key)
+		if err != nil {
+			return nil, err
+		}
+
+		values, ok := value.([]interface{})
+		if !ok {
+			values = []interface{}{value}
+		}
+
+		var valueConds []Condition
+		for _, v := range values {
+			fm, err := newFieldMatch(field, op, v)
+			if err != nil {
+				return nil, err
+			}
+			valueConds = append(valueConds, fm)
+		}
+
+		if len(valueConds) == 1 {
+			fieldConds = append(fieldConds, valueConds[0])
+		} else {
+			fieldConds = append(fieldConds, &orNode{children: valueConds})
+		}
+	}
+
+	if len(fieldConds) == 0 {
+		return nil, fmt.Errorf("selection has no fields")
+	}
+	if len(fieldConds) == 1 {
+		return fieldConds[0], nil
+	}
+	return &andNode{children: fieldConds}, nil
+}
+
+// parseFieldKey splits a Sigma detection key ("CommandLine|contains") into
+// its field name and operator, defaulting to equals when there's no "|".
+func parseFieldKey(key string) (string, fieldOp, error) {
+	parts := strings.SplitN(key, "|", 2)
+	field := parts[0]
+	if len(parts) == 1 {
+		return field, opEquals, nil
+	}
+
+	switch fieldOp(parts[1]) {
+	case opContains, opStartsWith, opEndsWith, opRegex, opGT, opGTE, opLT, opLTE:
+		return field, fieldOp(parts[1]), nil
+	default:
+		return "", "", fmt.Errorf("field %q: unknown modifier %q", field, parts[1])
+	}
+}
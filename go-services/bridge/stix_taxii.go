@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"regexp"
+	"sync"
+	"time"
+)
+
+// stixIPv4Pattern and stixHashPattern extract the IOC value out of a STIX
+// 2.1 indicator's "pattern" field for the two observable types this
+// provider understands:
+//
+//	[ipv4-addr:value = '1.2.3.4']
+//	[file:hashes.'SHA-256' = 'deadbeef...']
+//
+// STIX's pattern grammar supports arbitrary boolean combinations of
+// observables; matching the common single-comparison case by regexp instead
+// of writing a full pattern parser keeps this provider proportional to what
+// a bridge-side IOC lookup needs.
+var (
+	stixIPv4Pattern = regexp.MustCompile(`ipv4-addr:value\s*=\s*'([^']+)'`)
+	stixHashPattern = regexp.MustCompile(`file:hashes\.'[^']+'\s*=\s*'([^']+)'`)
+)
+
+// stixBundle is the subset of a STIX 2.1 bundle this provider cares about.
+type stixBundle struct {
+	Objects []struct {
+		Type    string `json:"type"`
+		Name    string `json:"name"`
+		Pattern string `json:"pattern"`
+	} `json:"objects"`
+}
+
+// STIXTAXIIProvider matches event fields against IOC values extracted from a
+// STIX 2.1 bundle fetched from a TAXII 2.1 collection's "objects" endpoint,
+// refreshed on an interval so new indicators show up without a bridge
+// restart.
+type STIXTAXIIProvider struct {
+	url string
+
+	mu     sync.RWMutex
+	ipv4   map[string]string
+	hashes map[string]string
+}
+
+// NewSTIXTAXIIProvider fetches url once synchronously (so the bridge doesn't
+// start with an empty indicator set) and then refreshes it in the
+// background every refreshInterval.
+func NewSTIXTAXIIProvider(url string, refreshInterval time.Duration) (*STIXTAXIIProvider, error) {
+	p := &STIXTAXIIProvider{url: url, ipv4: map[string]string{}, hashes: map[string]string{}}
+	if err := p.refresh(); err != nil {
+		return nil, err
+	}
+	if refreshInterval > 0 {
+		go p.watchRefresh(refreshInterval)
+	}
+	return p, nil
+}
+
+func (p *STIXTAXIIProvider) refresh() error {
+	resp, err := http.Get(p.url)
+	if err != nil {
+		return fmt.Errorf("stix-taxii: fetching collection: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("stix-taxii: unexpected status %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("stix-taxii: reading collection: %w", err)
+	}
+
+	var bundle stixBundle
+	if err := json.Unmarshal(body, &bundle); err != nil {
+		return fmt.Errorf("stix-taxii: parsing bundle: %w", err)
+	}
+
+	ipv4 := make(map[string]string, len(bundle.Objects))
+	hashes := make(map[string]string, len(bundle.Objects))
+	for _, obj := range bundle.Objects {
+		if obj.Type != "indicator" {
+			continue
+		}
+		if m := stixIPv4Pattern.FindStringSubmatch(obj.Pattern); m != nil {
+			ipv4[m[1]] = obj.Name
+		}
+		if m := stixHashPattern.FindStringSubmatch(obj.Pattern); m != nil {
+			hashes[m[1]] = obj.Name
+		}
+	}
+
+	p.mu.Lock()
+	p.ipv4 = ipv4
+	p.hashes = hashes
+	p.mu.Unlock()
+	return nil
+}
+
+func (p *STIXTAXIIProvider) watchRefresh(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for range ticker.C {
+		if err := p.refresh(); err != nil {
+			log.Printf("⚠️ stix-taxii refresh failed: %v", err)
+		}
+	}
+}
+
+func (p *STIXTAXIIProvider) Name() string { return "stix-taxii" }
+
+// match returns the indicator name matching ip or hash, if either is a
+// known IOC.
+func (p *STIXTAXIIProvider) match(ip, hash string) (string, bool) {
+	p.mu.RLock()
+	defer p.mu.RUnlock()
+
+	if ip != "" {
+		if name, ok := p.ipv4[ip]; ok {
+			return name, true
+		}
+	}
+	if hash != "" {
+		if name, ok := p.hashes[hash]; ok {
+			return name, true
+		}
+	}
+	return "", false
+}
+
+// Enrich implements EventEnricher: it checks the event's source IP, file
+// hash, and destination IP against the cached indicator set, recording a
+// match in ThreatIntelligenceMatch the same way the per-IP threat-intel
+// providers record theirs in EnrichmentResult.
+func (p *STIXTAXIIProvider) Enrich(ctx context.Context, event *UltraSIEMEvent) error {
+	if name, ok := p.match(event.SourceIP, event.FileHash); ok {
+		event.ThreatIntelligenceMatch = appendThreatIntelMatch(event.ThreatIntelligenceMatch, "stix:"+name)
+		return nil
+	}
+	if name, ok := p.match(event.DestinationIP, ""); ok {
+		event.ThreatIntelligenceMatch = appendThreatIntelMatch(event.ThreatIntelligenceMatch, "stix:"+name)
+	}
+	return nil
+}
+
+// appendThreatIntelMatch appends label to an existing comma-separated match
+// string, mirroring mergeEnrichment's ThreatIntelligenceMatch handling.
+func appendThreatIntelMatch(existing, label string) string {
+	if existing == "" {
+		return label
+	}
+	return existing + "," + label
+}
@@ -0,0 +1,283 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetricgrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/propagation"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the bridge's spans and OTel metric
+// instruments in whatever backend OTLPEndpoint points at.
+const instrumentationName = "github.com/YASSER-MN/ultra-siem/go-services/bridge"
+
+// otelShutdownFunc stops an exporter/provider started by initTracer or
+// initMeter; it's a no-op when OTLPEndpoint isn't configured.
+type otelShutdownFunc func(context.Context) error
+
+// initTracer builds the bridge's trace.Tracer. With no OTLPEndpoint
+// configured it returns the global (no-op) tracer, so span calls at every
+// call site stay cheap and harmless rather than needing a nil check.
+func initTracer(ctx context.Context, config *BridgeConfig) (trace.Tracer, otelShutdownFunc, error) {
+	if config.OTLPEndpoint == "" {
+		return otel.Tracer(instrumentationName), func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlptracegrpc.New(ctx,
+		otlptracegrpc.WithEndpoint(config.OTLPEndpoint),
+		otlptracegrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating OTLP trace exporter: %w", err)
+	}
+
+	tp := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.TraceSamplingRatio))),
+	)
+	otel.SetTracerProvider(tp)
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	return tp.Tracer(instrumentationName), tp.Shutdown, nil
+}
+
+// initMeter builds the bridge's metric.Meter, used for the ClickHouse
+// rows-written/insert-latency instruments that belong in the same OTLP
+// backend as traces rather than on the Prometheus /metrics endpoint. With
+// no OTLPEndpoint configured it returns the global (no-op) meter.
+func initMeter(ctx context.Context, config *BridgeConfig) (metric.Meter, otelShutdownFunc, error) {
+	if config.OTLPEndpoint == "" {
+		return otel.Meter(instrumentationName), func(context.Context) error { return nil }, nil
+	}
+
+	exporter, err := otlpmetricgrpc.New(ctx,
+		otlpmetricgrpc.WithEndpoint(config.OTLPEndpoint),
+		otlpmetricgrpc.WithInsecure(),
+	)
+	if err != nil {
+		return nil, nil, fmt.Errorf("creating OTLP metric exporter: %w", err)
+	}
+
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)))
+	otel.SetMeterProvider(mp)
+
+	return mp.Meter(instrumentationName), mp.Shutdown, nil
+}
+
+// clickhouseInstruments holds the OTel metrics recorded around ClickHouse
+// batch inserts, alongside the PrepareBatch/batch.Send spans.
+type clickhouseInstruments struct {
+	rowsWritten   metric.Int64Counter
+	insertLatency metric.Float64Histogram
+}
+
+// newClickhouseInstruments creates the rows-written counter and
+// insert-latency histogram against meter.
+func newClickhouseInstruments(meter metric.Meter) (*clickhouseInstruments, error) {
+	rowsWritten, err := meter.Int64Counter("ultra_siem.bridge.clickhouse.rows_written",
+		metric.WithDescription("Rows successfully inserted into ClickHouse"))
+	if err != nil {
+		return nil, fmt.Errorf("creating rows_written counter: %w", err)
+	}
+
+	insertLatency, err := meter.Float64Histogram("ultra_siem.bridge.clickhouse.insert_duration",
+		metric.WithDescription("Time spent on a single successful ClickHouse batch insert"),
+		metric.WithUnit("s"))
+	if err != nil {
+		return nil, fmt.Errorf("creating insert_duration histogram: %w", err)
+	}
+
+	return &clickhouseInstruments{rowsWritten: rowsWritten, insertLatency: insertLatency}, nil
+}
+
+// bridgeMetrics holds the bridge's Prometheus instruments, exposed on
+// MetricsPort when BridgeConfig.EnableMetrics is set. Metric names share
+// the "ultra_siem_" prefix the processor's metrics already use, so a
+// dashboard built against one service's naming reads the other's too.
+type bridgeMetrics struct {
+	enrichmentLatency      prometheus.Histogram
+	batchSize              *prometheus.HistogramVec
+	batchFlushDuration     *prometheus.HistogramVec
+	natsRedeliveries       *prometheus.CounterVec
+	eventsPerSubject       *prometheus.CounterVec
+	consumerLag            *prometheus.GaugeVec
+	threatsDetected        prometheus.Counter
+	enrichmentsPerformed   prometheus.Counter
+	clickhouseInsertErrors *prometheus.CounterVec
+	eventsByTypeAndSource  *prometheus.CounterVec
+	eventEnricherDuration  *prometheus.HistogramVec
+	eventEnricherErrors    *prometheus.CounterVec
+	dlqMessages            *prometheus.CounterVec
+}
+
+// newBridgeMetrics creates and registers the bridge's Prometheus
+// instruments against the default registry.
+func newBridgeMetrics() *bridgeMetrics {
+	m := &bridgeMetrics{
+		enrichmentLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ultra_siem_bridge_enrichment_duration_seconds",
+			Help:    "Time spent enriching a single event's source IP",
+			Buckets: prometheus.DefBuckets,
+		}),
+		batchSize: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ultra_siem_bridge_batch_size",
+			Help:    "Number of rows in each ClickHouse insert batch, labeled by table",
+			Buckets: []float64{1, 10, 50, 100, 250, 500, 1000},
+		}, []string{"table"}),
+		batchFlushDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ultra_siem_bridge_batch_flush_duration_seconds",
+			Help:    "Time spent flushing a batch (including retries), labeled by table",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"table"}),
+		natsRedeliveries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ultra_siem_bridge_nats_redeliveries_total",
+			Help: "Total number of JetStream messages received with NumDelivered > 1, labeled by table",
+		}, []string{"table"}),
+		eventsPerSubject: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ultra_siem_bridge_events_total",
+			Help: "Total number of messages pulled off each subject",
+		}, []string{"subject"}),
+		consumerLag: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ultra_siem_bridge_consumer_lag",
+			Help: "JetStream pull consumer NumPending, labeled by table",
+		}, []string{"table"}),
+		threatsDetected: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ultra_siem_bridge_threats_detected_total",
+			Help: "Total number of threat events inserted into ultra_siem.threats",
+		}),
+		enrichmentsPerformed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ultra_siem_bridge_enrichments_total",
+			Help: "Total number of events run through enrichUltraSIEMEvent",
+		}),
+		clickhouseInsertErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ultra_siem_bridge_clickhouse_insert_errors_total",
+			Help: "Total number of batch inserts that failed after exhausting retries, labeled by table",
+		}, []string{"table"}),
+		eventsByTypeAndSource: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ultra_siem_bridge_events_by_type_total",
+			Help: "Total number of ultra_siem.events rows built, labeled by event_type and log_source",
+		}, []string{"event_type", "log_source"}),
+		eventEnricherDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "ultra_siem_bridge_event_enricher_duration_seconds",
+			Help:    "Time spent in a single EventEnricher's Enrich call, labeled by enricher",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"enricher"}),
+		eventEnricherErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ultra_siem_bridge_event_enricher_errors_total",
+			Help: "Total number of EventEnricher.Enrich calls that returned an error or timed out, labeled by enricher",
+		}, []string{"enricher"}),
+		dlqMessages: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ultra_siem_bridge_dlq_messages_total",
+			Help: "Total number of messages dead-lettered, labeled by table and reason",
+		}, []string{"table", "reason"}),
+	}
+
+	prometheus.MustRegister(
+		m.enrichmentLatency,
+		m.batchSize,
+		m.batchFlushDuration,
+		m.natsRedeliveries,
+		m.eventsPerSubject,
+		m.consumerLag,
+		m.threatsDetected,
+		m.enrichmentsPerformed,
+		m.clickhouseInsertErrors,
+		m.eventsByTypeAndSource,
+		m.eventEnricherDuration,
+		m.eventEnricherErrors,
+		m.dlqMessages,
+	)
+
+	return m
+}
+
+// startMetricsServer exposes /metrics on config.MetricsPort, the same way
+// the processor's startMetricsServer does on PROCESSOR_METRICS_PORT, plus
+// /healthz (process liveness) and /readyz (NATS + ClickHouse connectivity)
+// so the bridge can be scraped and health-checked alongside ClickHouse
+// itself.
+func (b *SimpleBridge) startMetricsServer() {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+	mux.HandleFunc("/healthz", b.handleHealthz)
+	mux.HandleFunc("/readyz", b.handleReadyz)
+
+	port := b.config.MetricsPort
+	log.Printf("📈 Starting bridge metrics server on :%d", port)
+	if err := http.ListenAndServe(fmt.Sprintf(":%d", port), mux); err != nil {
+		log.Printf("⚠️ metrics server error: %v", err)
+	}
+}
+
+// handleHealthz reports liveness: the process is up and serving requests.
+// It deliberately doesn't check NATS/ClickHouse — that's /readyz's job —
+// so a transient dependency outage doesn't get the pod killed.
+func (b *SimpleBridge) handleHealthz(w http.ResponseWriter, r *http.Request) {
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// handleReadyz reports readiness: whether NATS is connected and
+// ClickHouse responds to a ping, so a load balancer or orchestrator can
+// stop routing traffic here without killing the process.
+func (b *SimpleBridge) handleReadyz(w http.ResponseWriter, r *http.Request) {
+	if !b.nc.IsConnected() {
+		http.Error(w, "nats: not connected", http.StatusServiceUnavailable)
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(r.Context(), 2*time.Second)
+	defer cancel()
+	if err := b.db.Ping(ctx); err != nil {
+		http.Error(w, fmt.Sprintf("clickhouse: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	w.Write([]byte("ok"))
+}
+
+// natsHeaderCarrier adapts a nats.Header (itself modeled on net/http.Header)
+// to OTel's propagation.TextMapCarrier, so a W3C traceparent set by an
+// upstream producer can be extracted from a NATS message's headers.
+type natsHeaderCarrier nats.Header
+
+func (c natsHeaderCarrier) Get(key string) string {
+	return nats.Header(c).Get(key)
+}
+
+func (c natsHeaderCarrier) Set(key, value string) {
+	nats.Header(c).Set(key, value)
+}
+
+func (c natsHeaderCarrier) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	return keys
+}
+
+// extractTraceContext pulls a W3C traceparent out of msg's NATS headers, if
+// present, so the span started for this message joins the producer's trace
+// instead of starting a new one.
+func extractTraceContext(ctx context.Context, msg *nats.Msg) context.Context {
+	if msg.Header == nil {
+		return ctx
+	}
+	return otel.GetTextMapPropagator().Extract(ctx, natsHeaderCarrier(msg.Header))
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+)
+
+// broadcasterSubscriberBuffer is the per-subscriber channel capacity before
+// a slow consumer starts having events dropped for it rather than slowing
+// down every other subscriber or the producer.
+const broadcasterSubscriberBuffer = 256
+
+// ThreatEventBroadcaster fans every ingested event out to any number of
+// in-process subscribers (alerters, correlation engines, WebSocket
+// pushers) without those subscribers having to drain the ring buffer
+// themselves. Delivery is non-blocking: a subscriber whose channel is full
+// has the event dropped for it (counted) instead of backing up the
+// broadcaster or the event that triggered it.
+type ThreatEventBroadcaster struct {
+	mu          sync.RWMutex
+	subscribers map[int]chan *ThreatEvent
+	nextID      int
+	metrics     *processorMetrics // may be nil in tests; Publish guards against that
+}
+
+// NewThreatEventBroadcaster returns an empty broadcaster. metrics may be
+// nil, in which case dropped events simply aren't counted.
+func NewThreatEventBroadcaster(metrics *processorMetrics) *ThreatEventBroadcaster {
+	return &ThreatEventBroadcaster{
+		subscribers: make(map[int]chan *ThreatEvent),
+		metrics:     metrics,
+	}
+}
+
+// Subscribe registers a new subscriber and returns its channel along with a
+// cancel func that unregisters it and closes the channel. Callers must
+// invoke cancel when done, or the subscriber (and its buffered channel)
+// leaks for the broadcaster's lifetime. cancel is safe to call more than
+// once.
+func (b *ThreatEventBroadcaster) Subscribe() (<-chan *ThreatEvent, func()) {
+	ch := make(chan *ThreatEvent, broadcasterSubscriberBuffer)
+
+	b.mu.Lock()
+	id := b.nextID
+	b.nextID++
+	b.subscribers[id] = ch
+	b.mu.Unlock()
+
+	cancel := func() {
+		b.mu.Lock()
+		if _, ok := b.subscribers[id]; ok {
+			delete(b.subscribers, id)
+			close(ch)
+		}
+		b.mu.Unlock()
+	}
+	return ch, cancel
+}
+
+// Publish delivers event to every current subscriber without blocking. A
+// subscriber whose channel is already full has the event dropped for it
+// and counted via ultra_siem_processor_broadcast_drops_total, rather than
+// stalling delivery to every other subscriber.
+func (b *ThreatEventBroadcaster) Publish(event *ThreatEvent) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+
+	for _, ch := range b.subscribers {
+		select {
+		case ch <- event:
+		default:
+			if b.metrics != nil {
+				b.metrics.broadcastDrops.Inc()
+			}
+		}
+	}
+}
+
+// SubscriberCount reports how many subscribers are currently registered.
+func (b *ThreatEventBroadcaster) SubscriberCount() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.subscribers)
+}
+
+// broadcasterHealthSummary is the JSON payload carried in the Payload field
+// of the synthetic ThreatEvent startHealthTicker publishes.
+type broadcasterHealthSummary struct {
+	SubscriberCount     int         `json:"subscriber_count"`
+	SubscriberLag       map[int]int `json:"subscriber_lag"`
+	RingBufferOccupancy float64     `json:"ring_buffer_occupancy"`
+}
+
+// broadcasterHealthThreatType is the ThreatType tagging startHealthTicker's
+// synthetic events, so subscribers can tell them apart from real detections.
+const broadcasterHealthThreatType = "broadcaster_health"
+
+// startHealthTicker periodically publishes a broadcasterHealthThreatType
+// event summarizing subscriber lag (how many events are queued in each
+// subscriber's channel) and ring buffer occupancy, so a subscriber watching
+// the broadcaster can see backpressure building up without also scraping
+// Prometheus. It runs until ctx is done.
+func (b *ThreatEventBroadcaster) startHealthTicker(ctx context.Context, interval time.Duration, ringBuffer *RingBuffer) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				b.mu.RLock()
+				lag := make(map[int]int, len(b.subscribers))
+				for id, ch := range b.subscribers {
+					lag[id] = len(ch)
+				}
+				count := len(b.subscribers)
+				b.mu.RUnlock()
+
+				payload, err := json.Marshal(broadcasterHealthSummary{
+					SubscriberCount:     count,
+					SubscriberLag:       lag,
+					RingBufferOccupancy: ringBuffer.Occupancy(),
+				})
+				if err != nil {
+					log.Printf("broadcaster: failed to marshal health summary: %v", err)
+					continue
+				}
+
+				b.Publish(&ThreatEvent{
+					Timestamp:  uint64(time.Now().Unix()),
+					ThreatType: broadcasterHealthThreatType,
+					Payload:    string(payload),
+				})
+			}
+		}
+	}()
+}
@@ -0,0 +1,127 @@
+package main
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+// TestBroadcasterSubscribeAndPublish tests that a subscriber receives an
+// event published after it subscribes.
+func TestBroadcasterSubscribeAndPublish(t *testing.T) {
+	b := NewThreatEventBroadcaster(nil)
+	ch, cancel := b.Subscribe()
+	defer cancel()
+
+	if got := b.SubscriberCount(); got != 1 {
+		t.Fatalf("expected 1 subscriber, got %d", got)
+	}
+
+	event := &ThreatEvent{SourceIP: "10.0.0.1", ThreatType: "test"}
+	b.Publish(event)
+
+	select {
+	case got := <-ch:
+		if got != event {
+			t.Errorf("expected to receive the published event, got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for published event")
+	}
+}
+
+// TestBroadcasterCancelUnsubscribes tests that cancel removes the
+// subscriber and closes its channel, and is safe to call twice.
+func TestBroadcasterCancelUnsubscribes(t *testing.T) {
+	b := NewThreatEventBroadcaster(nil)
+	ch, cancel := b.Subscribe()
+	cancel()
+	cancel() // must not panic
+
+	if got := b.SubscriberCount(); got != 0 {
+		t.Errorf("expected 0 subscribers after cancel, got %d", got)
+	}
+
+	if _, ok := <-ch; ok {
+		t.Error("expected channel to be closed after cancel")
+	}
+}
+
+// TestBroadcasterSlowConsumerDrops tests that a subscriber whose channel is
+// already full has events dropped for it instead of blocking Publish, and
+// that the drop is counted.
+func TestBroadcasterSlowConsumerDrops(t *testing.T) {
+	metrics := &processorMetrics{broadcastDrops: prometheus.NewCounter(prometheus.CounterOpts{Name: "test_broadcast_drops"})}
+	b := NewThreatEventBroadcaster(metrics)
+	ch, cancel := b.Subscribe()
+	defer cancel()
+
+	// Fill the subscriber's buffer, then publish one more than it can hold.
+	for i := 0; i < broadcasterSubscriberBuffer; i++ {
+		b.Publish(&ThreatEvent{ThreatType: "fill"})
+	}
+	b.Publish(&ThreatEvent{ThreatType: "overflow"})
+
+	if got := testutil.ToFloat64(metrics.broadcastDrops); got != 1 {
+		t.Errorf("expected 1 dropped event, got %v", got)
+	}
+
+	// Draining the channel should yield exactly the buffered "fill" events,
+	// never "overflow".
+	for i := 0; i < broadcasterSubscriberBuffer; i++ {
+		event := <-ch
+		if event.ThreatType != "fill" {
+			t.Fatalf("expected only buffered events, got %q", event.ThreatType)
+		}
+	}
+	select {
+	case event := <-ch:
+		t.Fatalf("expected no more events, got %+v", event)
+	default:
+	}
+}
+
+// TestBroadcasterConcurrentSubscribeUnsubscribe races Subscribe, cancel,
+// and Publish against each other under the race detector.
+func TestBroadcasterConcurrentSubscribeUnsubscribe(t *testing.T) {
+	b := NewThreatEventBroadcaster(nil)
+
+	var wg sync.WaitGroup
+	stop := make(chan struct{})
+
+	// Publisher.
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				b.Publish(&ThreatEvent{ThreatType: "race"})
+			}
+		}
+	}()
+
+	// Subscribers that immediately unsubscribe.
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, cancel := b.Subscribe()
+			cancel()
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		time.Sleep(50 * time.Millisecond)
+		close(stop)
+	}()
+
+	wg.Wait()
+}
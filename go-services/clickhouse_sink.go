@@ -0,0 +1,41 @@
+package main
+
+import "sync"
+
+// clickHouseSink adapts writeBatch's existing retry/circuit-breaker/DLQ
+// machinery to the Sink interface: Write only buffers, Flush is where the
+// real insert (and, on permanent failure, the threats.dlq publish) happens.
+type clickHouseSink struct {
+	mu     sync.Mutex
+	buffer []*ThreatEvent
+	insert func([]*ThreatEvent) error
+}
+
+// newClickHouseSink wraps insert (typically (*SIEMProcessor).writeBatch) in
+// a Sink, so ClickHouse is just one more registered destination rather than
+// a hardcoded step in the worker loop.
+func newClickHouseSink(insert func([]*ThreatEvent) error) *clickHouseSink {
+	return &clickHouseSink{insert: insert}
+}
+
+func (c *clickHouseSink) Write(event *ThreatEvent) error {
+	c.mu.Lock()
+	c.buffer = append(c.buffer, event)
+	c.mu.Unlock()
+	return nil
+}
+
+func (c *clickHouseSink) Flush() error {
+	c.mu.Lock()
+	batch := c.buffer
+	c.buffer = nil
+	c.mu.Unlock()
+	return c.insert(batch)
+}
+
+// Close flushes any buffered events; clickHouseSink holds no other
+// resources of its own (the ClickHouse connection is owned by
+// SIEMProcessor and closed separately).
+func (c *clickHouseSink) Close() error {
+	return c.Flush()
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"math/bits"
+	"sync/atomic"
+	"time"
+)
+
+// histogramBucketExponentShift and histogramBuckets size latencyHistogram's
+// fixed bucket array: bucketFor(nanos) is bits.Len64(nanos) shifted down by
+// histogramBucketExponentShift, clamped to [0, histogramBuckets-1]. Shifting
+// by 10 puts ~1µs (bits.Len64(1_000) == 10) at bucket 0 and ~10s
+// (bits.Len64(10_000_000_000) == 34) at bucket 24, comfortably inside a
+// 32-entry array — the "artisanal histogram" trick of getting power-of-two
+// buckets from a single CPU instruction instead of a binary search over
+// explicit bounds.
+const (
+	histogramBucketExponentShift = 10
+	histogramBuckets             = 32
+)
+
+// latencyHistogram is a fixed-bucket histogram of durations, stored as an
+// array of atomically-incremented counters. Observe is allocation-free and
+// lock-free (one bits.Len64 call and one atomic.AddUint32), cheap enough
+// to call on every RingBuffer Put/Get and every SIEMProcessor pipeline
+// stage.
+type latencyHistogram struct {
+	buckets [histogramBuckets]uint32
+}
+
+// bucketFor maps a duration in nanoseconds to its bucket index; each
+// bucket covers roughly a 2x range of durations.
+func bucketFor(nanos int64) int {
+	if nanos < 1 {
+		return 0
+	}
+	idx := bits.Len64(uint64(nanos)) - histogramBucketExponentShift
+	if idx < 0 {
+		return 0
+	}
+	if idx >= histogramBuckets {
+		return histogramBuckets - 1
+	}
+	return idx
+}
+
+// Observe records a single duration.
+func (h *latencyHistogram) Observe(d time.Duration) {
+	atomic.AddUint32(&h.buckets[bucketFor(int64(d))], 1)
+}
+
+// HistogramSnapshot is Snapshot's result: the raw per-bucket counts plus
+// percentiles computed from them, ready to publish to /metrics.
+type HistogramSnapshot struct {
+	Buckets             [histogramBuckets]uint32
+	P50, P95, P99, P999 time.Duration
+}
+
+// Snapshot reads every bucket counter and computes p50/p95/p99/p999 from
+// them. Percentiles are approximate: a bucket's upper bound is reported
+// for every observation that landed in it, so the error is at most that
+// bucket's ~2x width.
+func (h *latencyHistogram) Snapshot() HistogramSnapshot {
+	var snap HistogramSnapshot
+	var total uint64
+	for i := range h.buckets {
+		c := atomic.LoadUint32(&h.buckets[i])
+		snap.Buckets[i] = c
+		total += uint64(c)
+	}
+	if total == 0 {
+		return snap
+	}
+
+	snap.P50 = percentile(snap.Buckets[:], total, 0.50)
+	snap.P95 = percentile(snap.Buckets[:], total, 0.95)
+	snap.P99 = percentile(snap.Buckets[:], total, 0.99)
+	snap.P999 = percentile(snap.Buckets[:], total, 0.999)
+	return snap
+}
+
+// bucketUpperBound returns the largest duration that still maps to bucket
+// index i, i.e. bucketFor(int64(bucketUpperBound(i))) == i.
+func bucketUpperBound(i int) time.Duration {
+	return time.Duration(int64(1)<<uint(i+histogramBucketExponentShift) - 1)
+}
+
+// percentile walks buckets in ascending order, accumulating counts until
+// it reaches the requested fraction of total, and returns that bucket's
+// upper bound as the percentile's estimated value.
+func percentile(buckets []uint32, total uint64, fraction float64) time.Duration {
+	target := uint64(float64(total) * fraction)
+	var cumulative uint64
+	for i, c := range buckets {
+		cumulative += uint64(c)
+		if cumulative > target {
+			return bucketUpperBound(i)
+		}
+	}
+	return bucketUpperBound(len(buckets) - 1)
+}
@@ -0,0 +1,28 @@
+package main
+
+import "log"
+
+// logSink formats each event through a *log.Logger instead of writing it
+// anywhere durable, mainly for local debugging (SINK_STDOUT_LOG=true) or
+// piping into whatever already watches the process's own log output.
+type logSink struct {
+	logger *log.Logger
+}
+
+// newLogSink wraps logger; pass log.Default() to interleave events with the
+// processor's own log lines.
+func newLogSink(logger *log.Logger) *logSink {
+	return &logSink{logger: logger}
+}
+
+func (s *logSink) Write(event *ThreatEvent) error {
+	s.logger.Printf("event source_ip=%s threat_type=%s severity=%d confidence=%.2f",
+		event.SourceIP, event.ThreatType, event.Severity, event.Confidence)
+	return nil
+}
+
+// Flush is a no-op: log.Logger writes are unbuffered.
+func (s *logSink) Flush() error { return nil }
+
+// Close is a no-op: logSink doesn't own logger's underlying writer.
+func (s *logSink) Close() error { return nil }
@@ -18,6 +18,13 @@
 // - CLICKHOUSE_HOST: ClickHouse server address (default: clickhouse:9000)
 // - CLICKHOUSE_USER: ClickHouse username (default: admin)
 // - CLICKHOUSE_PASSWORD: ClickHouse password (default: admin)
+// - JETSTREAM_STREAM: JetStream stream backing threats.> (default: THREATS)
+// - JETSTREAM_DURABLE: Durable consumer name (default: siem-processor)
+// - JETSTREAM_REPLAY_POLICY: "all", "by_sequence", or "by_time" (default: "all")
+// - JETSTREAM_START_SEQUENCE: Starting sequence for "by_sequence" replay
+// - JETSTREAM_START_TIME: RFC3339 starting time for "by_time" replay
+// - SINK_FILE_PATH: if set, also mirror every event to this file as length-prefixed frames (see writer_sink.go / reader.go)
+// - SINK_STDOUT_LOG: "true" to also log every event via the standard logger
 //
 // Usage:
 //
@@ -34,16 +41,26 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
+	"math/rand"
 	"os"
+	"os/signal"
 	"runtime"
+	"strconv"
+	"strings"
+	"sync"
 	"sync/atomic"
+	"syscall"
 	"time"
 
 	"github.com/ClickHouse/clickhouse-go/v2"
 	"github.com/ClickHouse/clickhouse-go/v2/lib/driver"
 	"github.com/nats-io/nats.go"
+	"github.com/sony/gobreaker"
+
+	"github.com/YASSER-MN/ultra-siem/go-services/threats"
 )
 
 // ThreatEvent represents a security threat event in the SIEM system.
@@ -63,82 +80,204 @@ type ThreatEvent struct {
 	Severity uint8 `json:"severity"`
 	// Confidence score (0.0-1.0, higher = more confident)
 	Confidence float32 `json:"confidence"`
+
+	// natsMsg is the originating JetStream message, carried through the ring
+	// buffer so a worker can Ack it once the event has actually landed in
+	// ClickHouse. Unexported: it never round-trips through JSON and plain
+	// (non-JetStream) producers simply leave it nil.
+	natsMsg *nats.Msg
+}
+
+// fromCanonical converts a decoded threats.v1.ThreatEvent into the flat,
+// ring-buffer-friendly shape this processor has always used internally.
+// Fields the canonical schema added (Id, Metadata) are dropped here and
+// the byte Payload is carried as a string; the ring buffer's hot path only
+// needs what ClickHouse insertion already uses.
+func fromCanonical(e *threats.ThreatEvent) *ThreatEvent {
+	return &ThreatEvent{
+		Timestamp:  uint64(e.Timestamp.Unix()),
+		SourceIP:   e.SourceIP,
+		ThreatType: e.ThreatType,
+		Payload:    string(e.Payload),
+		Severity:   e.Severity,
+		Confidence: e.Confidence,
+	}
+}
+
+// toCanonical is fromCanonical's inverse, used by the DLQ replay worker to
+// re-validate a dead-lettered event against the current threats.v1 schema
+// before re-inserting it.
+func toCanonical(e *ThreatEvent) *threats.ThreatEvent {
+	return &threats.ThreatEvent{
+		Timestamp:  time.Unix(int64(e.Timestamp), 0).UTC(),
+		SourceIP:   e.SourceIP,
+		ThreatType: e.ThreatType,
+		Payload:    []byte(e.Payload),
+		Severity:   e.Severity,
+		Confidence: e.Confidence,
+	}
 }
 
 // RingBuffer provides a lock-free circular buffer for threat events.
 //
-// This implementation uses atomic operations to ensure thread-safety
-// without locks, enabling high-performance concurrent access.
+// This implementation uses atomic operations for the head/tail indices;
+// rb.mu is taken only to signal the sync.Conds (and, under DropOldest, for
+// the whole Put). Consumers that need to block
+// (Get, GetWithContext) wait on a sync.Cond instead of busy-spinning,
+// which is woken on every successful Put.
 // The buffer size must be a power of 2 for efficient modulo operations.
+//
+// Put's behavior when the buffer is full is governed by its configured
+// OverflowPolicy (DropNewest by default); see RingBufferOptions and
+// ring_buffer_overflow.go.
 type RingBuffer struct {
-	head   uint64           // Current read position
-	tail   uint64           // Current write position
-	mask   uint64           // Bit mask for efficient modulo (size - 1)
-	buffer []*ThreatEvent   // Circular array of events
+	head   uint64         // Current read position
+	tail   uint64         // Current write position
+	mask   uint64         // Bit mask for efficient modulo (size - 1)
+	buffer []*ThreatEvent // Circular array of events
+
+	mu       sync.Mutex
+	notEmpty *sync.Cond // Broadcast on every successful Put
+	notFull  *sync.Cond // Non-nil (and broadcast on every successful TryGet) only under BlockWithTimeout
+
+	policy       OverflowPolicy
+	blockTimeout time.Duration // used when policy == BlockWithTimeout
+	spill        *spillSegment // used when policy == SpillToDisk
+
+	putLatency     latencyHistogram // How long Put took to return, across every OverflowPolicy
+	getWaitLatency latencyHistogram // How long Get waited for an event to become available
 }
 
-// NewRingBuffer creates a new ring buffer with the specified size.
+// NewRingBuffer creates a new ring buffer with the specified size and, if
+// given, the overflow policy in opts (the first one; additional values are
+// ignored). With no opts, Put drops the newest event when full, matching
+// this type's original behavior.
 //
 // Args:
-//   size: Buffer size (must be a power of 2)
+//
+//	size: Buffer size (must be a power of 2)
+//	opts: Optional RingBufferOptions selecting an overflow policy
 //
 // Returns:
-//   *RingBuffer: New ring buffer instance
+//
+//	*RingBuffer: New ring buffer instance
 //
 // Panics:
-//   If size is not a power of 2
+//
+//	If size is not a power of 2
 //
 // Example:
-//   buffer := NewRingBuffer(1024) // Creates 1K event buffer
-func NewRingBuffer(size uint64) *RingBuffer {
+//
+//	buffer := NewRingBuffer(1024) // Creates 1K event buffer
+func NewRingBuffer(size uint64, opts ...RingBufferOptions) *RingBuffer {
 	// Ensure size is power of 2 for efficient modulo operations
 	if size&(size-1) != 0 {
 		panic("Ring buffer size must be power of 2")
 	}
-	
-	return &RingBuffer{
-		head:   0,
-		tail:   0,
-		mask:   size - 1,
-		buffer: make([]*ThreatEvent, size),
+
+	var opt RingBufferOptions
+	if len(opts) > 0 {
+		opt = opts[0]
+	}
+
+	rb := &RingBuffer{
+		head:         0,
+		tail:         0,
+		mask:         size - 1,
+		buffer:       make([]*ThreatEvent, size),
+		policy:       opt.Policy,
+		blockTimeout: opt.BlockTimeout,
+	}
+	rb.notEmpty = sync.NewCond(&rb.mu)
+	if opt.Policy == BlockWithTimeout {
+		rb.notFull = sync.NewCond(&rb.mu)
+	}
+
+	if opt.Policy == SpillToDisk {
+		spill, err := newSpillSegment(opt.SpillPath)
+		if err != nil {
+			panic(fmt.Sprintf("ring buffer: %v", err))
+		}
+		rb.spill = spill
+		rb.recoverSpill()
 	}
+
+	return rb
 }
 
 // Put adds an event to the ring buffer.
 //
 // This method is thread-safe and uses atomic operations to ensure
-// concurrent access without locks. If the buffer is full, it will
-// yield the CPU and retry.
+// concurrent access to head/tail; rb.mu is held only briefly to wake
+// waiting consumers (for DropOldest, across the whole Put). What happens
+// when the buffer is full depends on the policy NewRingBuffer was given:
+// see OverflowPolicy.
 //
 // Args:
-//   event: Threat event to add to buffer
+//
+//	event: Threat event to add to buffer
 //
 // Returns:
-//   bool: true if event was added successfully, false if buffer is full
+//
+//	bool: true if the event was accepted (buffered or, under SpillToDisk,
+//	durably spilled), false if it was dropped
 //
 // Example:
-//   success := buffer.Put(&ThreatEvent{...})
-//   if !success {
-//       log.Println("Buffer full, event dropped")
-//   }
+//
+//	success := buffer.Put(&ThreatEvent{...})
+//	if !success {
+//	    log.Println("Buffer full, event dropped")
+//	}
 func (rb *RingBuffer) Put(event *ThreatEvent) bool {
+	start := time.Now()
+	defer func() { rb.putLatency.Observe(time.Since(start)) }()
+
+	switch rb.policy {
+	case DropOldest:
+		return rb.putDropOldest(event)
+	case BlockWithTimeout:
+		return rb.putBlockWithTimeout(event)
+	case SpillToDisk:
+		return rb.putSpillToDisk(event)
+	default:
+		return rb.putDropNewest(event)
+	}
+}
+
+// tryPutOnce makes a single non-blocking attempt to append event, retrying
+// only while losing the CAS race to another writer; it returns false
+// (without retrying) as soon as the buffer is observed full. This is the
+// original lock-free Put body, shared by every OverflowPolicy's Put.
+func (rb *RingBuffer) tryPutOnce(event *ThreatEvent) bool {
 	for {
 		tail := atomic.LoadUint64(&rb.tail)
 		next := (tail + 1) & rb.mask
-		
+
 		if next == atomic.LoadUint64(&rb.head) {
-			// Buffer is full, yield CPU and retry
-			runtime.Gosched()
 			return false
 		}
-		
+
 		if atomic.CompareAndSwapUint64(&rb.tail, tail, next) {
 			rb.buffer[tail] = event
+			rb.mu.Lock()
+			rb.notEmpty.Broadcast()
+			rb.mu.Unlock()
 			return true
 		}
 	}
 }
 
+// putDropNewest is OverflowPolicy DropNewest's Put: on a full buffer it
+// yields the CPU once and drops the incoming event, leaving the buffer
+// unchanged. This is RingBuffer's original (and still default) behavior.
+func (rb *RingBuffer) putDropNewest(event *ThreatEvent) bool {
+	if rb.tryPutOnce(event) {
+		return true
+	}
+	runtime.Gosched()
+	return false
+}
+
 // Get retrieves an event from the ring buffer.
 //
 // This method is thread-safe and uses atomic operations. If the buffer
@@ -146,36 +285,156 @@ func (rb *RingBuffer) Put(event *ThreatEvent) bool {
 // becomes available.
 //
 // Returns:
-//   *ThreatEvent: Next event from buffer, or nil if buffer is empty
+//
+//	*ThreatEvent: Next event from buffer; on an empty buffer Get waits
+//	on notEmpty rather than returning nil
 //
 // Note:
-//   This method blocks until an event is available. For non-blocking
-//   behavior, check buffer status before calling.
+//
+//	This method blocks until an event is available. For non-blocking
+//	behavior, check buffer status before calling.
 //
 // Example:
-//   event := buffer.Get()
-//   if event != nil {
-//       processEvent(event)
-//   }
+//
+//	event := buffer.Get()
+//	if event != nil {
+//	    processEvent(event)
+//	}
 func (rb *RingBuffer) Get() *ThreatEvent {
+	start := time.Now()
+	for {
+		if event, ok := rb.TryGet(); ok {
+			rb.getWaitLatency.Observe(time.Since(start))
+			return event
+		}
+
+		rb.mu.Lock()
+		for rb.Empty() {
+			rb.notEmpty.Wait()
+		}
+		rb.mu.Unlock()
+	}
+}
+
+// PutLatency returns the histogram of how long Put takes to return, across
+// every OverflowPolicy, for publishing via startMetricsTracking.
+func (rb *RingBuffer) PutLatency() *latencyHistogram {
+	return &rb.putLatency
+}
+
+// GetWaitLatency returns the histogram of how long Get waits for an event
+// to become available, for publishing via startMetricsTracking.
+func (rb *RingBuffer) GetWaitLatency() *latencyHistogram {
+	return &rb.getWaitLatency
+}
+
+// TryGet retrieves an event from the ring buffer without blocking.
+//
+// Returns:
+//
+//	*ThreatEvent: Next event from buffer, or nil if the buffer is empty
+//	bool: true if an event was retrieved, false if the buffer was empty
+//
+// Example:
+//
+//	if event, ok := buffer.TryGet(); ok {
+//	    processEvent(event)
+//	}
+func (rb *RingBuffer) TryGet() (*ThreatEvent, bool) {
 	for {
 		head := atomic.LoadUint64(&rb.head)
 		tail := atomic.LoadUint64(&rb.tail)
-		
+
 		if head == tail {
-			// Buffer is empty, yield CPU and retry
-			runtime.Gosched()
-			continue
+			return nil, false
 		}
-		
+
 		if atomic.CompareAndSwapUint64(&rb.head, head, (head+1)&rb.mask) {
 			event := rb.buffer[head]
 			rb.buffer[head] = nil // Help garbage collector
-			return event
+
+			// Wake any BlockWithTimeout writer waiting for room, and give
+			// SpillToDisk's overflow segment first claim on the slot that
+			// was just freed.
+			if rb.notFull != nil {
+				rb.mu.Lock()
+				rb.notFull.Broadcast()
+				rb.mu.Unlock()
+			}
+			if rb.spill != nil {
+				rb.reclaimFromSpill()
+			}
+
+			return event, true
+		}
+	}
+}
+
+// Close releases resources held by the ring buffer's overflow handling.
+// It's a no-op unless the buffer was created with OverflowPolicy
+// SpillToDisk, in which case it closes the backing segment file.
+func (rb *RingBuffer) Close() error {
+	if rb.spill != nil {
+		return rb.spill.close()
+	}
+	return nil
+}
+
+// GetWithContext retrieves an event from the ring buffer, waiting on the
+// notEmpty condition (instead of spinning) until one is available or ctx
+// is done. This lets callers (e.g. the insert workers) bound how long
+// they wait so a batch-timeout flush can still happen on schedule.
+//
+// Returns:
+//
+//	*ThreatEvent: Next event from buffer
+//	error: ctx.Err() if ctx is done before an event becomes available
+func (rb *RingBuffer) GetWithContext(ctx context.Context) (*ThreatEvent, error) {
+	for {
+		if event, ok := rb.TryGet(); ok {
+			return event, nil
+		}
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+
+		// Wake this wait early if ctx is cancelled/expires while we're
+		// parked on the cond, since Cond.Wait only reacts to Broadcast.
+		stop := context.AfterFunc(ctx, func() {
+			rb.mu.Lock()
+			rb.notEmpty.Broadcast()
+			rb.mu.Unlock()
+		})
+
+		rb.mu.Lock()
+		for rb.Empty() && ctx.Err() == nil {
+			rb.notEmpty.Wait()
+		}
+		rb.mu.Unlock()
+		stop()
+
+		if err := ctx.Err(); err != nil {
+			return nil, err
 		}
 	}
 }
 
+// Empty reports whether the buffer currently has no events to drain.
+// Used during shutdown to stop polling once the buffer has been drained,
+// since Get otherwise blocks forever on an empty buffer.
+func (rb *RingBuffer) Empty() bool {
+	return atomic.LoadUint64(&rb.head) == atomic.LoadUint64(&rb.tail)
+}
+
+// Occupancy reports how full the ring buffer currently is, from 0 (empty)
+// to just under 1 (full), for the ring_buffer_occupancy_ratio gauge.
+func (rb *RingBuffer) Occupancy() float64 {
+	tail := atomic.LoadUint64(&rb.tail)
+	head := atomic.LoadUint64(&rb.head)
+	used := (tail - head) & rb.mask
+	return float64(used) / float64(rb.mask+1)
+}
+
 // SIEMProcessor manages the main SIEM data processing pipeline.
 //
 // This struct coordinates all components of the data processing system:
@@ -184,14 +443,129 @@ func (rb *RingBuffer) Get() *ThreatEvent {
 // - Ring buffer for event buffering
 // - Worker goroutines for parallel processing
 // - Statistics tracking and reporting
+// - Broadcasting a copy of every event to in-process subscribers
 type SIEMProcessor struct {
-	nats       *nats.Conn    // NATS connection for messaging
-	clickhouse driver.Conn   // ClickHouse connection for storage
-	ringBuffer *RingBuffer   // Event buffer for processing
-	stats      struct {
+	nats           *nats.Conn                // NATS connection for messaging
+	js             nats.JetStreamContext     // JetStream context for durable consumption
+	clickhouse     driver.Conn               // ClickHouse connection for storage
+	ringBuffer     *RingBuffer               // Event buffer for processing
+	jsConfig       *JetStreamConfig          // Durable consumer / replay configuration
+	circuitBreaker *gobreaker.CircuitBreaker // Guards the ClickHouse insert path
+	sink           Sink                      // Where processed events end up (ClickHouse + any configured extras)
+	broadcaster    *ThreatEventBroadcaster   // Fans out a copy of every ingested event to in-process subscribers
+	metrics        *processorMetrics         // Prometheus metrics + rolling-window rate tracker
+	shutdown       chan struct{}             // Closed to signal workers to drain and stop
+	drained        chan struct{}             // Closed once all workers have exited
+	ctx            context.Context           // Cancelled by Shutdown; bounds background goroutines like the broadcaster's health ticker
+	cancel         context.CancelFunc
+	stats          struct {
 		processed uint64 // Total events processed successfully
 		errors    uint64 // Total processing errors
 	}
+
+	// Per-stage latency histograms, published alongside the ring buffer's
+	// via startMetricsTracking. enrichLatency times fromCanonical's
+	// canonical-to-flat conversion: this processor doesn't enrich events
+	// itself (that happens upstream in the bridge service), but that
+	// conversion step sits in the same place in this pipeline, so it's
+	// what "enrich" measures here.
+	parseLatency     latencyHistogram
+	enrichLatency    latencyHistogram
+	sinkWriteLatency latencyHistogram
+}
+
+// latencyStages names every pipeline-stage latency histogram the processor
+// tracks, for startMetricsTracking to snapshot and publish uniformly.
+func (sp *SIEMProcessor) latencyStages() map[string]*latencyHistogram {
+	return map[string]*latencyHistogram{
+		"ring_buffer_put":      sp.ringBuffer.PutLatency(),
+		"ring_buffer_get_wait": sp.ringBuffer.GetWaitLatency(),
+		"parse":                &sp.parseLatency,
+		"enrich":               &sp.enrichLatency,
+		"sink_write":           &sp.sinkWriteLatency,
+	}
+}
+
+// JetStreamConfig controls the durable pull consumer used to read
+// "threats.>" events so that downtime or ring-buffer overflow no longer
+// means permanently dropped events.
+//
+// Configuration:
+// - JETSTREAM_STREAM: Stream name backing threats.> (default: THREATS)
+// - JETSTREAM_DURABLE: Durable consumer name (default: siem-processor)
+// - JETSTREAM_REPLAY_POLICY: "all", "by_sequence", or "by_time" (default: "all")
+// - JETSTREAM_START_SEQUENCE: Starting sequence for "by_sequence" replay
+// - JETSTREAM_START_TIME: RFC3339 starting time for "by_time" replay
+type JetStreamConfig struct {
+	StreamName    string
+	DurableName   string
+	ReplayPolicy  string
+	StartSequence uint64
+	StartTime     time.Time
+}
+
+// jetStreamConfigFromEnv builds a JetStreamConfig from the process
+// environment, matching the precedence used throughout this service
+// (env var if set, otherwise a sane default).
+func jetStreamConfigFromEnv() *JetStreamConfig {
+	cfg := &JetStreamConfig{
+		StreamName:   getEnv("JETSTREAM_STREAM", "THREATS"),
+		DurableName:  getEnv("JETSTREAM_DURABLE", "siem-processor"),
+		ReplayPolicy: getEnv("JETSTREAM_REPLAY_POLICY", "all"),
+	}
+
+	if seq := os.Getenv("JETSTREAM_START_SEQUENCE"); seq != "" {
+		if parsed, err := strconv.ParseUint(seq, 10, 64); err == nil {
+			cfg.StartSequence = parsed
+		}
+	}
+
+	if start := os.Getenv("JETSTREAM_START_TIME"); start != "" {
+		if parsed, err := time.Parse(time.RFC3339, start); err == nil {
+			cfg.StartTime = parsed
+		}
+	}
+
+	return cfg
+}
+
+// deliverPolicyOption translates the configured replay mode into the
+// nats.Option consumers need to start from the right place in the stream.
+func (c *JetStreamConfig) deliverPolicyOption() nats.SubOpt {
+	switch c.ReplayPolicy {
+	case "by_sequence":
+		return nats.StartSequence(c.StartSequence)
+	case "by_time":
+		return nats.StartTime(c.StartTime)
+	default:
+		return nats.DeliverAll()
+	}
+}
+
+// ensureStream auto-provisions the JetStream stream backing "threats.>" if
+// it doesn't already exist, so a fresh deployment doesn't need an operator
+// to run `nats stream add` by hand.
+func ensureStream(js nats.JetStreamContext, cfg *JetStreamConfig) error {
+	_, err := js.StreamInfo(cfg.StreamName)
+	if err == nil {
+		return nil
+	}
+	if err != nats.ErrStreamNotFound {
+		return fmt.Errorf("failed to look up stream %s: %w", cfg.StreamName, err)
+	}
+
+	_, err = js.AddStream(&nats.StreamConfig{
+		Name:      cfg.StreamName,
+		Subjects:  []string{"threats.>"},
+		Storage:   nats.FileStorage,
+		Retention: nats.LimitsPolicy,
+		MaxAge:    7 * 24 * time.Hour,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create stream %s: %w", cfg.StreamName, err)
+	}
+
+	return nil
 }
 
 // NewSIEMProcessor creates and initializes a new SIEM processor.
@@ -204,29 +578,41 @@ type SIEMProcessor struct {
 // 5. Returns a ready-to-use processor
 //
 // Returns:
-//   *SIEMProcessor: Initialized processor instance
-//   error: Any error that occurred during initialization
+//
+//	*SIEMProcessor: Initialized processor instance
+//	error: Any error that occurred during initialization
 //
 // Example:
-//   processor, err := NewSIEMProcessor()
-//   if err != nil {
-//       log.Fatal("Failed to create processor:", err)
-//   }
+//
+//	processor, err := NewSIEMProcessor()
+//	if err != nil {
+//	    log.Fatal("Failed to create processor:", err)
+//	}
 func NewSIEMProcessor() (*SIEMProcessor, error) {
 	// Connect to NATS with authentication
 	natsURL := getEnv("NATS_URL", "nats://localhost:4222")
 	natsPassword := getEnv("NATS_PROCESSOR_PASSWORD", "ultra_siem_processor_2024")
-	
+
 	opts := []nats.Option{
 		nats.Name("ultra-siem-processor"),
 		nats.UserInfo("processor", natsPassword),
 	}
-	
+
 	nc, err := nats.Connect(natsURL, opts...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
 	}
 
+	js, err := nc.JetStream()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get JetStream context: %w", err)
+	}
+
+	jsConfig := jetStreamConfigFromEnv()
+	if err := ensureStream(js, jsConfig); err != nil {
+		return nil, err
+	}
+
 	// Connect to ClickHouse
 	clickhouseHost := getEnv("CLICKHOUSE_HOST", "clickhouse:9000")
 	clickhouseUser := getEnv("CLICKHOUSE_USER", "admin")
@@ -267,11 +653,45 @@ func NewSIEMProcessor() (*SIEMProcessor, error) {
 		return nil, fmt.Errorf("failed to create table: %w", err)
 	}
 
-	return &SIEMProcessor{
-		nats:       nc,
-		clickhouse: ch,
-		ringBuffer: NewRingBuffer(1 << 20), // 1M elements
-	}, nil
+	circuitBreaker := gobreaker.NewCircuitBreaker(gobreaker.Settings{
+		Name:        "clickhouse-insert",
+		MaxRequests: 3,
+		Interval:    10 * time.Second,
+		Timeout:     30 * time.Second,
+		ReadyToTrip: func(counts gobreaker.Counts) bool {
+			failureRatio := float64(counts.TotalFailures) / float64(counts.Requests)
+			return counts.Requests >= 3 && failureRatio >= 0.6
+		},
+		OnStateChange: func(name string, from gobreaker.State, to gobreaker.State) {
+			log.Printf("Circuit breaker %s: %s -> %s", name, from, to)
+		},
+	})
+
+	metrics := newProcessorMetrics()
+	ctx, cancel := context.WithCancel(context.Background())
+
+	processor := &SIEMProcessor{
+		nats:           nc,
+		js:             js,
+		clickhouse:     ch,
+		ringBuffer:     NewRingBuffer(1 << 20), // 1M elements
+		jsConfig:       jsConfig,
+		circuitBreaker: circuitBreaker,
+		broadcaster:    NewThreatEventBroadcaster(metrics),
+		metrics:        metrics,
+		shutdown:       make(chan struct{}),
+		drained:        make(chan struct{}),
+		ctx:            ctx,
+		cancel:         cancel,
+	}
+
+	sink, err := buildSink(processor)
+	if err != nil {
+		return nil, err
+	}
+	processor.sink = sink
+
+	return processor, nil
 }
 
 // startWorkers launches the ClickHouse insertion worker goroutines.
@@ -285,33 +705,109 @@ func NewSIEMProcessor() (*SIEMProcessor, error) {
 // - Batches events for efficient database insertion
 // - Handles insertion errors gracefully
 // - Updates processing statistics
+//
+// Workers pull events with GetWithContext bounded by a deadline tied to
+// the batch's 100ms timeout, so a partial batch actually gets flushed on
+// schedule instead of waiting on Get() to return an event that may never
+// come. The wait is also bounded by sp.ctx, so cancelling it (Shutdown)
+// wakes every worker immediately instead of leaving them to time out on
+// their own; each worker then drains whatever's left in the ring buffer,
+// flushes it, and exits. sp.drained is closed once every worker has
+// returned, which is what Shutdown waits on before handing control back to
+// main() to close the sinks.
 func (sp *SIEMProcessor) startWorkers() {
 	numWorkers := runtime.GOMAXPROCS(0)
 	log.Printf("Starting %d ClickHouse workers", numWorkers)
-	
+
+	const batchTimeout = 100 * time.Millisecond
+
+	var wg sync.WaitGroup
+	wg.Add(numWorkers)
+	go func() {
+		wg.Wait()
+		close(sp.drained)
+	}()
+
 	for i := 0; i < numWorkers; i++ {
 		go func(workerID int) {
+			defer wg.Done()
 			runtime.LockOSThread()
-			
+
 			// Batch for efficient inserts
 			batch := make([]*ThreatEvent, 0, 1000)
 			lastFlush := time.Now()
-			
+
+			flush := func() {
+				if len(batch) == 0 {
+					return
+				}
+				sp.metrics.batchSize.Observe(float64(len(batch)))
+
+				if err := sp.writeThroughSink(batch); err != nil {
+					// Both the insert and the DLQ publish failed: Nak
+					// so JetStream redelivers rather than losing the
+					// batch outright.
+					log.Printf("Worker %d: Insert error: %v", workerID, err)
+					atomic.AddUint64(&sp.stats.errors, 1)
+					sp.metrics.eventsErrors.Inc()
+					for _, e := range batch {
+						if e.natsMsg != nil {
+							if nakErr := e.natsMsg.Nak(); nakErr != nil {
+								log.Printf("Worker %d: Nak error: %v", workerID, nakErr)
+							}
+						}
+					}
+				} else {
+					atomic.AddUint64(&sp.stats.processed, uint64(len(batch)))
+					sp.metrics.eventsProcessed.Add(float64(len(batch)))
+					// Ack once the batch is durably in ClickHouse or
+					// has been safely routed to threats.dlq, so a
+					// crash before this point leads to JetStream
+					// redelivery rather than silent loss.
+					for _, e := range batch {
+						if e.natsMsg != nil {
+							if err := e.natsMsg.Ack(); err != nil {
+								log.Printf("Worker %d: Ack error: %v", workerID, err)
+							}
+						}
+					}
+				}
+
+				batch = batch[:0]
+				lastFlush = time.Now()
+			}
+
 			for {
-				event := sp.ringBuffer.Get()
-				batch = append(batch, event)
-				
-				// Flush when batch is full or timeout reached
-				if len(batch) >= 1000 || time.Since(lastFlush) > 100*time.Millisecond {
-					if err := sp.insertBatch(batch); err != nil {
-						log.Printf("Worker %d: Insert error: %v", workerID, err)
-						atomic.AddUint64(&sp.stats.errors, 1)
-					} else {
-						atomic.AddUint64(&sp.stats.processed, uint64(len(batch)))
+				if sp.ctx.Err() != nil {
+					// Shutdown has been requested: drain whatever's left
+					// without waiting for more, flush it, and return so
+					// Shutdown can safely hand off to main()'s deferred
+					// sink/connection closes.
+					for {
+						event, ok := sp.ringBuffer.TryGet()
+						if !ok {
+							break
+						}
+						batch = append(batch, event)
+						if len(batch) >= 1000 {
+							flush()
+						}
 					}
-					
-					batch = batch[:0]
-					lastFlush = time.Now()
+					flush()
+					return
+				}
+
+				waitCtx, cancel := context.WithDeadline(sp.ctx, lastFlush.Add(batchTimeout))
+				event, err := sp.ringBuffer.GetWithContext(waitCtx)
+				cancel()
+
+				if err == nil {
+					batch = append(batch, event)
+				}
+
+				// Flush when batch is full or timeout reached
+				if len(batch) >= 1000 || time.Since(lastFlush) >= batchTimeout {
+					flush()
 				}
 			}
 		}(i)
@@ -322,13 +818,13 @@ func (sp *SIEMProcessor) insertBatch(events []*ThreatEvent) error {
 	if len(events) == 0 {
 		return nil
 	}
-	
+
 	batch, err := sp.clickhouse.PrepareBatch(context.Background(),
 		"INSERT INTO threats (timestamp, source_ip, threat_type, payload, severity, confidence)")
 	if err != nil {
 		return err
 	}
-	
+
 	for _, event := range events {
 		err = batch.Append(
 			time.Unix(int64(event.Timestamp), 0),
@@ -342,42 +838,276 @@ func (sp *SIEMProcessor) insertBatch(events []*ThreatEvent) error {
 			return err
 		}
 	}
-	
+
 	return batch.Send()
 }
 
+// Retry tuning for writeBatch: exponential backoff with jitter, capped at
+// insertMaxBackoff, up to maxInsertRetries attempts before a batch is
+// dead-lettered.
+const (
+	maxInsertRetries  = 5
+	insertBaseBackoff = 100 * time.Millisecond
+	insertMaxBackoff  = 5 * time.Second
+)
+
+// isTransientCHError reports whether err looks like a transient condition
+// worth retrying (network hiccup, timeout, an overloaded circuit breaker)
+// as opposed to a permanent one (schema mismatch, auth failure) that should
+// go straight to the dead-letter queue instead of being retried forever.
+func isTransientCHError(err error) bool {
+	if errors.Is(err, gobreaker.ErrOpenState) || errors.Is(err, gobreaker.ErrTooManyRequests) {
+		return true
+	}
+
+	msg := strings.ToLower(err.Error())
+	transientSubstrings := []string{
+		"too many parts",
+		"timeout",
+		"timed out",
+		"connection refused",
+		"connection reset",
+		"broken pipe",
+		"i/o timeout",
+		"no route to host",
+		"unavailable",
+	}
+	for _, s := range transientSubstrings {
+		if strings.Contains(msg, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// writeBatch inserts events into ClickHouse behind sp.circuitBreaker,
+// retrying transient errors with exponential backoff and jitter. A
+// permanent error, or a transient one still failing after
+// maxInsertRetries, routes the batch to threats.dlq instead of losing it.
+func (sp *SIEMProcessor) writeBatch(events []*ThreatEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	var lastErr error
+	for attempt := 0; attempt < maxInsertRetries; attempt++ {
+		start := time.Now()
+		_, err := sp.circuitBreaker.Execute(func() (interface{}, error) {
+			return nil, sp.insertBatch(events)
+		})
+		sp.metrics.insertLatency.Observe(time.Since(start).Seconds())
+		if err == nil {
+			return nil
+		}
+		lastErr = err
+
+		if !isTransientCHError(err) {
+			return sp.deadLetter(events, err, true)
+		}
+		if attempt == maxInsertRetries-1 {
+			break
+		}
+
+		backoff := insertBaseBackoff * time.Duration(1<<uint(attempt))
+		if backoff > insertMaxBackoff {
+			backoff = insertMaxBackoff
+		}
+		jitter := time.Duration(rand.Int63n(int64(backoff)))
+		time.Sleep(backoff/2 + jitter/2)
+	}
+
+	return sp.deadLetter(events, lastErr, false)
+}
+
+// writeThroughSink hands events to sp.sink one at a time and then flushes
+// it, so every configured sink (ClickHouse plus any SINK_FILE_PATH /
+// SINK_STDOUT_LOG extras) sees the same batch instead of ClickHouse alone.
+// Individual Write errors are logged rather than aborting the batch, since
+// MultiSink already continues fanning out to the remaining sinks; the
+// return value is whatever Flush reports (ClickHouse's retry/DLQ result).
+func (sp *SIEMProcessor) writeThroughSink(events []*ThreatEvent) error {
+	start := time.Now()
+	defer func() { sp.sinkWriteLatency.Observe(time.Since(start)) }()
+
+	for _, e := range events {
+		if err := sp.sink.Write(e); err != nil {
+			log.Printf("sink write error: %v", err)
+		}
+	}
+	return sp.sink.Flush()
+}
+
+// dlqEnvelope is the payload published to threats.dlq when a batch can't be
+// written to ClickHouse, carrying enough context for an operator (or
+// startDLQReplayWorker) to diagnose and replay it later.
+type dlqEnvelope struct {
+	Events    []*ThreatEvent `json:"events"`
+	Reason    string         `json:"reason"`
+	FailedAt  time.Time      `json:"failed_at"`
+	Permanent bool           `json:"permanent"`
+}
+
+// deadLetter publishes a failed batch to threats.dlq instead of silently
+// dropping it.
+func (sp *SIEMProcessor) deadLetter(events []*ThreatEvent, cause error, permanent bool) error {
+	envelope := dlqEnvelope{
+		Events:    events,
+		Reason:    cause.Error(),
+		FailedAt:  time.Now().UTC(),
+		Permanent: permanent,
+	}
+	payload, err := json.Marshal(envelope)
+	if err != nil {
+		return fmt.Errorf("failed to marshal DLQ envelope: %w", err)
+	}
+	if _, err := sp.js.Publish("threats.dlq", payload); err != nil {
+		return fmt.Errorf("failed to publish to DLQ (original error: %v): %w", cause, err)
+	}
+
+	log.Printf("Routed %d events to threats.dlq (permanent=%v): %v", len(events), permanent, cause)
+	return nil
+}
+
+// startDLQReplayWorker subscribes to threats.dlq and re-validates each
+// dead-lettered event against the current threats.v1 schema before
+// re-inserting it, giving operators a recoverable path once the underlying
+// ClickHouse issue is fixed instead of having to replay events by hand.
+func (sp *SIEMProcessor) startDLQReplayWorker() error {
+	_, err := sp.js.Subscribe("threats.dlq", func(m *nats.Msg) {
+		var envelope dlqEnvelope
+		if err := json.Unmarshal(m.Data, &envelope); err != nil {
+			log.Printf("DLQ replay: failed to unmarshal envelope: %v", err)
+			if nakErr := m.Nak(); nakErr != nil {
+				log.Printf("DLQ replay: failed to Nak: %v", nakErr)
+			}
+			return
+		}
+
+		valid := make([]*ThreatEvent, 0, len(envelope.Events))
+		for _, e := range envelope.Events {
+			if err := threats.Validate(toCanonical(e)); err != nil {
+				log.Printf("DLQ replay: dropping event that fails schema validation: %v", err)
+				continue
+			}
+			valid = append(valid, e)
+		}
+
+		if err := sp.writeThroughSink(valid); err != nil {
+			log.Printf("DLQ replay: re-insert failed, leaving message for redelivery: %v", err)
+			if nakErr := m.Nak(); nakErr != nil {
+				log.Printf("DLQ replay: failed to Nak: %v", nakErr)
+			}
+			return
+		}
+
+		if err := m.Ack(); err != nil {
+			log.Printf("DLQ replay: failed to Ack: %v", err)
+		}
+	},
+		nats.Durable(sp.jsConfig.DurableName+"-dlq-replay"),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		nats.DeliverAll(),
+	)
+	if err != nil {
+		return err
+	}
+
+	log.Println("DLQ replay worker started (subject=threats.dlq)")
+	return nil
+}
+
+// startNATSConsumer subscribes to threats.detected as a named, durable
+// JetStream consumer instead of a plain core-NATS subscription, so that
+// processor downtime or ring-buffer overflow no longer drops events
+// permanently — unacked messages are simply redelivered.
+//
+// The consumer's replay mode (DeliverAll / DeliverByStartSequence /
+// DeliverByStartTime) is controlled by JetStreamConfig, which is built
+// from the JETSTREAM_* environment variables.
 func (sp *SIEMProcessor) startNATSConsumer() error {
-	sub, err := sp.nats.Subscribe("threats.detected", func(m *nats.Msg) {
-		var event ThreatEvent
-		if err := json.Unmarshal(m.Data, &event); err != nil {
+	sub, err := sp.js.Subscribe("threats.detected", func(m *nats.Msg) {
+		// Decode via the shared threats.v1 schema registry so producers
+		// on the legacy flat JSON shape (schema v0) and the canonical
+		// wire format (schema v1, proto or JSON) are both accepted; see
+		// go-services/threats for the Schema-Id/Content-Type contract.
+		parseStart := time.Now()
+		canonical, err := threats.DecodeMessage(m.Header, m.Data)
+		sp.parseLatency.Observe(time.Since(parseStart))
+		if err != nil {
 			log.Printf("Failed to unmarshal event: %v", err)
+			if nakErr := m.Nak(); nakErr != nil {
+				log.Printf("Failed to Nak unparseable message: %v", nakErr)
+			}
 			return
 		}
-		
-		// Try to put in ring buffer, drop if full
-		if !sp.ringBuffer.Put(&event) {
+
+		enrichStart := time.Now()
+		event := fromCanonical(canonical)
+		sp.enrichLatency.Observe(time.Since(enrichStart))
+
+		// Carry the message alongside the event so a worker can Ack it
+		// only after the corresponding ClickHouse batch has Send()'ed.
+		event.natsMsg = m
+
+		// Try to put in ring buffer, Nak (for redelivery) if full.
+		if !sp.ringBuffer.Put(event) {
 			atomic.AddUint64(&sp.stats.errors, 1)
+			if nakErr := m.Nak(); nakErr != nil {
+				log.Printf("Failed to Nak dropped message: %v", nakErr)
+			}
+		} else {
+			sp.broadcaster.Publish(event)
 		}
-	})
+	},
+		nats.Durable(sp.jsConfig.DurableName),
+		nats.ManualAck(),
+		nats.AckExplicit(),
+		sp.jsConfig.deliverPolicyOption(),
+	)
 	if err != nil {
 		return err
 	}
-	
+
 	// Configure for high performance
 	sub.SetPendingLimits(-1, -1)
-	
-	log.Println("NATS consumer started")
+
+	log.Printf("NATS JetStream consumer started (stream=%s durable=%s replay=%s)",
+		sp.jsConfig.StreamName, sp.jsConfig.DurableName, sp.jsConfig.ReplayPolicy)
 	return nil
 }
 
+// Shutdown cancels sp.ctx, which wakes every worker waiting on the ring
+// buffer, and waits for them to drain it, flush whatever was pending, and
+// exit (sp.drained) before returning. This guarantees workers are no
+// longer touching the ring buffer, ClickHouse, or sp.sink by the time
+// Shutdown returns, so main()'s deferred Close calls on those never race
+// a still-running worker.
+func (sp *SIEMProcessor) Shutdown(ctx context.Context) error {
+	close(sp.shutdown)
+	sp.cancel()
+
+	select {
+	case <-sp.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// startStatsReporter logs a summary every 10 seconds. The rate it reports
+// comes from sp.metrics's rolling-window tracker rather than
+// processed divided by time.Since(time.Now()), a near-zero divisor that
+// made the old "Rate=%.2f/sec" line meaningless.
 func (sp *SIEMProcessor) startStatsReporter() {
 	ticker := time.NewTicker(10 * time.Second)
 	go func() {
 		for range ticker.C {
 			processed := atomic.LoadUint64(&sp.stats.processed)
 			errors := atomic.LoadUint64(&sp.stats.errors)
-			log.Printf("Stats: Processed=%d, Errors=%d, Rate=%.2f/sec", 
-				processed, errors, float64(processed)/time.Since(time.Now()).Seconds())
+			rates := sp.metrics.rates.Snapshot()
+			log.Printf("Stats: Processed=%d, Errors=%d, Rate(1s/10s/1m)=%.2f/%.2f/%.2f per sec",
+				processed, errors, rates[rateWindow1s], rates[rateWindow10s], rates[rateWindow1m])
 		}
 	}()
 }
@@ -391,35 +1121,63 @@ func getEnv(key, defaultValue string) string {
 
 func main() {
 	log.Println("ðŸš€ Ultra SIEM Go Processor Starting...")
-	
+
 	// Set GOMAXPROCS if not set
 	if runtime.GOMAXPROCS(0) == 1 {
 		runtime.GOMAXPROCS(runtime.NumCPU())
 	}
-	
+
 	// Force GC to be more aggressive
 	runtime.GC()
-	
+
 	processor, err := NewSIEMProcessor()
 	if err != nil {
 		log.Fatalf("Failed to create processor: %v", err)
 	}
 	defer processor.clickhouse.Close()
 	defer processor.nats.Close()
-	
+	defer processor.sink.Close()
+	defer processor.ringBuffer.Close()
+
 	// Start workers
 	processor.startWorkers()
-	
+
+	// Start rolling-window rate tracking and the Prometheus /metrics
+	// endpoint, so operators have one scrape target per pod.
+	processor.startMetricsTracking()
+	go processor.startMetricsServer()
+
 	// Start NATS consumer
 	if err := processor.startNATSConsumer(); err != nil {
 		log.Fatalf("Failed to start NATS consumer: %v", err)
 	}
-	
+
+	// Start the dead-letter replay worker so batches that couldn't be
+	// written to ClickHouse get another chance once the issue clears.
+	if err := processor.startDLQReplayWorker(); err != nil {
+		log.Fatalf("Failed to start DLQ replay worker: %v", err)
+	}
+
 	// Start stats reporter
 	processor.startStatsReporter()
-	
+
+	// Periodically publish a broadcaster_health event summarizing
+	// subscriber lag and ring buffer occupancy to anyone subscribed via
+	// processor.broadcaster.
+	processor.broadcaster.startHealthTicker(processor.ctx, 10*time.Second, processor.ringBuffer)
+
 	log.Println("âœ… Processor ready for high-performance threat processing")
-	
-	// Keep running
-	select {}
-} 
\ No newline at end of file
+
+	// Block until SIGINT/SIGTERM, then drain and Ack pending batches
+	// before exiting.
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	<-sigChan
+
+	log.Println("ðŸ›‘ Shutdown signal received, draining pending batches...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	if err := processor.Shutdown(shutdownCtx); err != nil {
+		log.Printf("Shutdown: error while draining: %v", err)
+	}
+}
@@ -1,9 +1,14 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"testing"
 	"time"
+
+	"github.com/sony/gobreaker"
 )
 
 // TestRingBuffer tests the lock-free ring buffer functionality
@@ -175,9 +180,76 @@ func BenchmarkRingBufferGet(b *testing.B) {
 	}
 }
 
+// BenchmarkRingBufferTryGet tests performance of the non-blocking variant,
+// which never parks on the notEmpty condition.
+func BenchmarkRingBufferTryGet(b *testing.B) {
+	buffer := NewRingBuffer(1024)
+	event := &ThreatEvent{
+		Timestamp:  uint64(time.Now().Unix()),
+		SourceIP:   "192.168.1.1",
+		ThreatType: "benchmark",
+		Payload:    "benchmark payload",
+		Severity:   5,
+		Confidence: 0.8,
+	}
+
+	// Pre-fill buffer
+	for i := 0; i < 1000; i++ {
+		buffer.Put(event)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, ok := buffer.TryGet(); !ok {
+			// Buffer drained; keep it non-empty for the rest of the run.
+			buffer.Put(event)
+		}
+	}
+}
+
+// BenchmarkRingBufferGetWithContext measures the cost of the cond-based
+// wait path under saturation, with a producer goroutine keeping the
+// buffer fed so consumers practically never have to wait. This is the
+// throughput/CPU comparison point against the old Gosched spin-loop,
+// which burned a full core busy-waiting under the same conditions.
+func BenchmarkRingBufferGetWithContext(b *testing.B) {
+	buffer := NewRingBuffer(1 << 16)
+	event := &ThreatEvent{
+		Timestamp:  uint64(time.Now().Unix()),
+		SourceIP:   "192.168.1.1",
+		ThreatType: "benchmark",
+		Payload:    "benchmark payload",
+		Severity:   5,
+		Confidence: 0.8,
+	}
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				buffer.Put(event)
+			}
+		}
+	}()
+	defer close(stop)
+
+	ctx := context.Background()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := buffer.GetWithContext(ctx); err != nil {
+			b.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
 // TestConcurrentRingBufferAccess tests thread safety
 func TestConcurrentRingBufferAccess(t *testing.T) {
-	buffer := NewRingBuffer(1000)
+	// NewRingBuffer panics unless size is a power of 2, and this is sized to
+	// comfortably hold all numGoroutines*numOperations puts below.
+	buffer := NewRingBuffer(1024)
 	numGoroutines := 10
 	numOperations := 100
 
@@ -218,4 +290,33 @@ func TestConcurrentRingBufferAccess(t *testing.T) {
 	if eventsRetrieved == 0 {
 		t.Error("No events were retrieved from buffer")
 	}
-} 
\ No newline at end of file
+}
+
+// TestIsTransientCHError tests the transient/permanent classification that
+// decides whether writeBatch retries a ClickHouse error or dead-letters it
+// immediately.
+func TestIsTransientCHError(t *testing.T) {
+	transient := []error{
+		errors.New("dial tcp: i/o timeout"),
+		errors.New("DB::Exception: Too many parts (600). Merges are processing significantly slower than inserts"),
+		errors.New("connection reset by peer"),
+		gobreaker.ErrOpenState,
+		gobreaker.ErrTooManyRequests,
+		fmt.Errorf("wrapped: %w", gobreaker.ErrOpenState),
+	}
+	for _, err := range transient {
+		if !isTransientCHError(err) {
+			t.Errorf("expected %q to be classified as transient", err)
+		}
+	}
+
+	permanent := []error{
+		errors.New("DB::Exception: Unknown column 'severity' in table 'threats'"),
+		errors.New("DB::Exception: Authentication failed"),
+	}
+	for _, err := range permanent {
+		if isTransientCHError(err) {
+			t.Errorf("expected %q to be classified as permanent", err)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"log"
+	"math"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Rolling windows tracked by rateTracker, also used as the "window" label
+// on the ultra_siem_processor_events_per_second gauge.
+const (
+	rateWindow1s  = "1s"
+	rateWindow10s = "10s"
+	rateWindow1m  = "1m"
+)
+
+// rateTrackerTick is how often rateTracker samples sp.stats.processed. All
+// EWMA decay constants are derived from this, the same way go-metrics'
+// EWMA assumes a 5s tick.
+const rateTrackerTick = 1 * time.Second
+
+// rateTracker turns a monotonically increasing counter into a handful of
+// exponentially weighted moving average rates, so "events/sec" reflects
+// recent throughput instead of being computed against a near-zero elapsed
+// time (the bug this replaces divided by time.Since(time.Now())).
+type rateTracker struct {
+	mu      sync.Mutex
+	windows map[string]*ewma
+	last    uint64
+	source  *uint64
+}
+
+// newRateTracker builds a tracker sampling source (via atomic.LoadUint64)
+// once per rateTrackerTick.
+func newRateTracker(source *uint64) *rateTracker {
+	return &rateTracker{
+		windows: map[string]*ewma{
+			rateWindow1s:  newEWMA(1 * time.Second),
+			rateWindow10s: newEWMA(10 * time.Second),
+			rateWindow1m:  newEWMA(1 * time.Minute),
+		},
+		source: source,
+	}
+}
+
+// tick samples the source counter and feeds the delta since the last tick
+// into every window's EWMA.
+func (rt *rateTracker) tick() {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	current := atomic.LoadUint64(rt.source)
+	delta := current - rt.last
+	rt.last = current
+
+	perSecond := float64(delta) / rateTrackerTick.Seconds()
+	for _, w := range rt.windows {
+		w.update(perSecond)
+	}
+}
+
+// Snapshot returns the current rate for each tracked window, keyed by the
+// rateWindow* constants.
+func (rt *rateTracker) Snapshot() map[string]float64 {
+	rt.mu.Lock()
+	defer rt.mu.Unlock()
+
+	out := make(map[string]float64, len(rt.windows))
+	for name, w := range rt.windows {
+		out[name] = w.rate
+	}
+	return out
+}
+
+// ewma is an exponentially weighted moving average over window, decaying
+// at rateTrackerTick resolution — the same shape as go-metrics' EWMA type.
+type ewma struct {
+	alpha  float64
+	rate   float64
+	primed bool
+}
+
+func newEWMA(window time.Duration) *ewma {
+	return &ewma{alpha: 1 - math.Exp(-rateTrackerTick.Seconds()/window.Seconds())}
+}
+
+func (e *ewma) update(instantRate float64) {
+	if !e.primed {
+		e.rate = instantRate
+		e.primed = true
+		return
+	}
+	e.rate += e.alpha * (instantRate - e.rate)
+}
+
+// processorMetrics holds the processor's Prometheus instruments and the
+// rolling-window rate tracker behind its events/sec gauge. Metric names
+// share the "ultra_siem_" prefix OptimizedBridge's Metrics already uses, so
+// a dashboard built against one service's naming reads the other's too.
+type processorMetrics struct {
+	eventsProcessed     prometheus.Counter
+	eventsErrors        prometheus.Counter
+	eventsPerSecond     *prometheus.GaugeVec
+	batchSize           prometheus.Histogram
+	ringBufferOccupancy prometheus.Gauge
+	insertLatency       prometheus.Histogram
+	broadcastDrops      prometheus.Counter
+	stageLatencySeconds *prometheus.GaugeVec
+
+	rates *rateTracker
+}
+
+// newProcessorMetrics creates and registers the processor's Prometheus
+// instruments against the default registry.
+func newProcessorMetrics() *processorMetrics {
+	m := &processorMetrics{
+		eventsProcessed: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ultra_siem_processor_events_processed_total",
+			Help: "Total number of threat events inserted into ClickHouse by the processor",
+		}),
+		eventsErrors: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ultra_siem_processor_errors_total",
+			Help: "Total number of batch insert errors in the processor",
+		}),
+		eventsPerSecond: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ultra_siem_processor_events_per_second",
+			Help: "EWMA-smoothed events/sec processed, labeled by averaging window",
+		}, []string{"window"}),
+		batchSize: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ultra_siem_processor_batch_size",
+			Help:    "Number of events in each ClickHouse insert batch",
+			Buckets: []float64{1, 10, 50, 100, 250, 500, 1000},
+		}),
+		ringBufferOccupancy: prometheus.NewGauge(prometheus.GaugeOpts{
+			Name: "ultra_siem_processor_ring_buffer_occupancy_ratio",
+			Help: "Fraction of the ring buffer currently occupied (0-1)",
+		}),
+		insertLatency: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Name:    "ultra_siem_processor_clickhouse_insert_duration_seconds",
+			Help:    "Time spent on a single ClickHouse batch insert attempt",
+			Buckets: prometheus.DefBuckets,
+		}),
+		broadcastDrops: prometheus.NewCounter(prometheus.CounterOpts{
+			Name: "ultra_siem_processor_broadcast_drops_total",
+			Help: "Total number of events dropped for a slow ThreatEventBroadcaster subscriber whose channel was full",
+		}),
+		stageLatencySeconds: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Name: "ultra_siem_processor_stage_latency_seconds",
+			Help: "Latency percentiles for each pipeline stage, computed from its latencyHistogram",
+		}, []string{"stage", "quantile"}),
+	}
+
+	prometheus.MustRegister(
+		m.eventsProcessed,
+		m.eventsErrors,
+		m.eventsPerSecond,
+		m.batchSize,
+		m.ringBufferOccupancy,
+		m.insertLatency,
+		m.broadcastDrops,
+		m.stageLatencySeconds,
+	)
+
+	return m
+}
+
+// publishStageLatencies snapshots every pipeline-stage latencyHistogram and
+// sets its p50/p95/p99/p999 on the stage_latency_seconds gauge, so they
+// show up on the same /metrics scrape as everything else.
+func (sp *SIEMProcessor) publishStageLatencies() {
+	for stage, h := range sp.latencyStages() {
+		snap := h.Snapshot()
+		sp.metrics.stageLatencySeconds.WithLabelValues(stage, "p50").Set(snap.P50.Seconds())
+		sp.metrics.stageLatencySeconds.WithLabelValues(stage, "p95").Set(snap.P95.Seconds())
+		sp.metrics.stageLatencySeconds.WithLabelValues(stage, "p99").Set(snap.P99.Seconds())
+		sp.metrics.stageLatencySeconds.WithLabelValues(stage, "p999").Set(snap.P999.Seconds())
+	}
+}
+
+// startMetricsTracking ticks the rate tracker and the ring-buffer occupancy
+// gauge every rateTrackerTick until sp.shutdown is closed.
+func (sp *SIEMProcessor) startMetricsTracking() {
+	sp.metrics.rates = newRateTracker(&sp.stats.processed)
+
+	ticker := time.NewTicker(rateTrackerTick)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				sp.metrics.rates.tick()
+				for window, rate := range sp.metrics.rates.Snapshot() {
+					sp.metrics.eventsPerSecond.WithLabelValues(window).Set(rate)
+				}
+				sp.metrics.ringBufferOccupancy.Set(sp.ringBuffer.Occupancy())
+				sp.publishStageLatencies()
+			case <-sp.shutdown:
+				return
+			}
+		}
+	}()
+}
+
+// startMetricsServer exposes /metrics on PROCESSOR_METRICS_PORT (default
+// 9090), so an operator gets one scrape target per pod the same way
+// OptimizedBridge already does on :8080.
+func (sp *SIEMProcessor) startMetricsServer() {
+	port := getEnv("PROCESSOR_METRICS_PORT", "9090")
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.Handler())
+
+	log.Printf("Starting processor metrics server on :%s", port)
+	if err := http.ListenAndServe(":"+port, mux); err != nil {
+		log.Printf("Metrics server error: %v", err)
+	}
+}
@@ -5,81 +5,318 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/nats-io/nats.go"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 	"github.com/sony/gobreaker"
-	"google.golang.org/protobuf/proto"
-	"google.golang.org/protobuf/types/known/timestamppb"
+	"golang.org/x/time/rate"
+
+	"github.com/YASSER-MN/ultra-siem/go-services/threats"
 )
 
+// flowControlSubject is where an overloaded bridge tells upstream Rust/Zeek
+// collectors to slow down.
+const flowControlSubject = "threats.flowcontrol"
+
+// rateLimitControlSubject lets operators reconfigure per-threat-type rate
+// limits at runtime without restarting the bridge.
+const rateLimitControlSubject = "ultra-siem.ratelimit.control"
+
 // OptimizedBridge represents the enhanced data bridge with performance optimizations
 type OptimizedBridge struct {
-	nc              *nats.Conn
-	js              nats.JetStreamContext
-	circuitBreaker  *gobreaker.CircuitBreaker
-	connectionPool  *ConnectionPool
-	metrics         *Metrics
-	backpressure    *BackpressureHandler
-	requestBatcher  *RequestBatcher
+	nc               *nats.Conn
+	js               nats.JetStreamContext
+	circuitBreaker   *gobreaker.CircuitBreaker
+	connectionPool   *ConnectionPool
+	metrics          *Metrics
+	backpressure     *BackpressureHandler
+	requestBatcher   *RequestBatcher
 	gracefulShutdown chan struct{}
-	wg              sync.WaitGroup
+	wg               sync.WaitGroup
 }
 
 // ConnectionPool manages NATS connections efficiently
 type ConnectionPool struct {
-	connections chan *nats.Conn
+	connections    chan *nats.Conn
 	maxConnections int
-	mu          sync.RWMutex
-	active      map[*nats.Conn]bool
+	mu             sync.RWMutex
+	active         map[*nats.Conn]bool
 }
 
-// BackpressureHandler manages flow control
+// BackpressureHandler paces ingestion with a real token bucket per threat
+// type (instead of just counting events in a fixed-size channel) and
+// signals upstream collectors via NATS when the bridge can't keep up.
 type BackpressureHandler struct {
-	rateLimiter chan struct{}
-	maxRate     int
-	mu          sync.RWMutex
-	currentRate int
+	mu       sync.RWMutex
+	limiters map[string]*rate.Limiter // keyed by ThreatType, "default" for unknown types
+	dropped  map[string]uint64        // events rejected by the limiter, per type
+	waits    map[string]*waitSampler  // Allow() wait-time samples, per type
+
+	// queueDepth is a legacy occupancy gauge: it stands in for the ring
+	// buffer occupancy this bridge doesn't otherwise track, and still
+	// feeds monitorBackpressure's >80%-full warning. It's incremented once
+	// per event and reset to 0 by monitorBackpressure's ticker every
+	// second, rather than a bounded channel (which would fill permanently
+	// after queueCap events and never reflect real load again).
+	queueDepth int64
+	queueCap   int64
+	maxRate    int
+
+	nc *nats.Conn // used to publish threats.flowcontrol and hear control updates
+}
+
+// rateLimitControlMessage is the payload accepted on
+// rateLimitControlSubject for dynamic reconfiguration.
+type rateLimitControlMessage struct {
+	ThreatType string  `json:"threat_type"`
+	RPS        float64 `json:"rate"`
+	Burst      int     `json:"burst"`
+}
+
+// handleControlMessage applies a dynamic rate/burst update published to
+// rateLimitControlSubject, so operators can retune limits without
+// restarting the bridge.
+func (bp *BackpressureHandler) handleControlMessage(msg *nats.Msg) {
+	var ctrl rateLimitControlMessage
+	if err := json.Unmarshal(msg.Data, &ctrl); err != nil {
+		log.Printf("⚠️ Invalid rate limit control message: %v", err)
+		return
+	}
+	if ctrl.ThreatType == "" || ctrl.RPS <= 0 || ctrl.Burst <= 0 {
+		log.Printf("⚠️ Rejecting rate limit control message with invalid fields: %+v", ctrl)
+		return
+	}
+	bp.reconfigure(ctrl.ThreatType, ctrl.RPS, ctrl.Burst)
+	log.Printf("🔧 Rate limit for %s reconfigured to %.1f rps / burst %d", ctrl.ThreatType, ctrl.RPS, ctrl.Burst)
+}
+
+// publishFlowControl tells upstream collectors to slow down. Best-effort:
+// a publish failure here shouldn't block the caller from continuing to
+// apply backpressure locally.
+func (bp *BackpressureHandler) publishFlowControl(threatType, reason string) {
+	if bp.nc == nil {
+		return
+	}
+	payload, err := json.Marshal(map[string]string{
+		"threat_type": threatType,
+		"reason":      reason,
+	})
+	if err != nil {
+		return
+	}
+	if err := bp.nc.Publish(flowControlSubject, payload); err != nil {
+		log.Printf("⚠️ Failed to publish flow control signal: %v", err)
+	}
+}
+
+// rateLimitConfig is the (rate, burst) pair configurable per threat type.
+type rateLimitConfig struct {
+	rps   float64
+	burst int
+}
+
+// defaultRateLimits seeds per-threat-type buckets; RATE_LIMIT_<TYPE>_RPS /
+// RATE_LIMIT_<TYPE>_BURST env vars (upper-cased threat type) override them,
+// and RATE_LIMIT_DEFAULT_RPS/_BURST override the fallback bucket used for
+// any threat type without its own entry.
+var defaultRateLimits = map[string]rateLimitConfig{
+	"malware":       {rps: 200, burst: 400},
+	"ddos":          {rps: 500, burst: 1000},
+	"sql_injection": {rps: 200, burst: 400},
+	"default":       {rps: 1000, burst: 2000},
+}
+
+// NewBackpressureHandler builds the per-threat-type limiter set from
+// defaultRateLimits, overridden by RATE_LIMIT_<TYPE>_{RPS,BURST} env vars.
+func NewBackpressureHandler() *BackpressureHandler {
+	bp := &BackpressureHandler{
+		limiters: make(map[string]*rate.Limiter),
+		dropped:  make(map[string]uint64),
+		waits:    make(map[string]*waitSampler),
+		queueCap: 1000, // 1000 events/sec max
+		maxRate:  1000,
+	}
+
+	for threatType, cfg := range defaultRateLimits {
+		envPrefix := "RATE_LIMIT_" + strings.ToUpper(threatType)
+		if v := os.Getenv(envPrefix + "_RPS"); v != "" {
+			if parsed, err := strconv.ParseFloat(v, 64); err == nil {
+				cfg.rps = parsed
+			}
+		}
+		if v := os.Getenv(envPrefix + "_BURST"); v != "" {
+			if parsed, err := strconv.Atoi(v); err == nil {
+				cfg.burst = parsed
+			}
+		}
+		bp.limiters[threatType] = rate.NewLimiter(rate.Limit(cfg.rps), cfg.burst)
+		bp.waits[threatType] = newWaitSampler()
+	}
+
+	return bp
+}
+
+// limiterFor returns the token bucket for threatType, falling back to the
+// shared "default" bucket (and lazily creating one from it) for types that
+// don't have a dedicated configuration.
+func (bp *BackpressureHandler) limiterFor(threatType string) *rate.Limiter {
+	bp.mu.RLock()
+	limiter, ok := bp.limiters[threatType]
+	bp.mu.RUnlock()
+	if ok {
+		return limiter
+	}
+
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if limiter, ok := bp.limiters[threatType]; ok {
+		return limiter
+	}
+	def := bp.limiters["default"]
+	limiter = rate.NewLimiter(def.Limit(), def.Burst())
+	bp.limiters[threatType] = limiter
+	bp.waits[threatType] = newWaitSampler()
+	return limiter
+}
+
+// reconfigure applies a dynamic rate/burst update to a single threat type's
+// bucket, creating it if necessary. Used by the NATS control subject so
+// operators don't have to restart the bridge to tune limits.
+func (bp *BackpressureHandler) reconfigure(threatType string, rps float64, burst int) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+
+	limiter, ok := bp.limiters[threatType]
+	if !ok {
+		limiter = rate.NewLimiter(rate.Limit(rps), burst)
+		bp.limiters[threatType] = limiter
+		bp.waits[threatType] = newWaitSampler()
+		return
+	}
+	limiter.SetLimit(rate.Limit(rps))
+	limiter.SetBurst(burst)
+}
+
+func (bp *BackpressureHandler) recordDrop(threatType string) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	bp.dropped[threatType]++
+}
+
+func (bp *BackpressureHandler) recordWait(threatType string, d time.Duration) {
+	bp.mu.RLock()
+	sampler, ok := bp.waits[threatType]
+	bp.mu.RUnlock()
+	if !ok {
+		return
+	}
+	sampler.observe(d)
+}
+
+// occupancyRatio reports how full the legacy queue gauge is, used as a
+// stand-in for ring-buffer occupancy when deciding to emit flow control.
+func (bp *BackpressureHandler) occupancyRatio() float64 {
+	return float64(atomic.LoadInt64(&bp.queueDepth)) / float64(bp.queueCap)
+}
+
+// snapshot renders the current state of every tracked bucket for the
+// /debug/ratelimit endpoint.
+func (bp *BackpressureHandler) snapshot() map[string]any {
+	bp.mu.RLock()
+	defer bp.mu.RUnlock()
+
+	out := make(map[string]any, len(bp.limiters))
+	for threatType, limiter := range bp.limiters {
+		out[threatType] = map[string]any{
+			"tokens":      limiter.Tokens(),
+			"rate":        float64(limiter.Limit()),
+			"burst":       limiter.Burst(),
+			"dropped":     bp.dropped[threatType],
+			"wait_p99_ms": bp.waits[threatType].p99().Seconds() * 1000,
+		}
+	}
+	return out
+}
+
+// waitSampler keeps a bounded window of recent Allow() wait-time
+// observations so the /debug/ratelimit endpoint can report a p99 without
+// growing unbounded memory under sustained load.
+type waitSampler struct {
+	mu      sync.Mutex
+	samples []time.Duration
+	next    int
+}
+
+const waitSamplerCapacity = 256
+
+func newWaitSampler() *waitSampler {
+	return &waitSampler{samples: make([]time.Duration, 0, waitSamplerCapacity)}
+}
+
+func (w *waitSampler) observe(d time.Duration) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) < waitSamplerCapacity {
+		w.samples = append(w.samples, d)
+		return
+	}
+	w.samples[w.next] = d
+	w.next = (w.next + 1) % waitSamplerCapacity
+}
+
+func (w *waitSampler) p99() time.Duration {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.samples) == 0 {
+		return 0
+	}
+	sorted := append([]time.Duration(nil), w.samples...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	idx := (len(sorted) * 99) / 100
+	if idx >= len(sorted) {
+		idx = len(sorted) - 1
+	}
+	return sorted[idx]
 }
 
 // RequestBatcher batches requests for efficiency
 type RequestBatcher struct {
-	batchSize   int
+	batchSize    int
 	batchTimeout time.Duration
-	batches     chan []interface{}
-	processor   func([]interface{}) error
+	batches      chan []interface{}
+	processor    func([]interface{}) error
 }
 
 // Metrics for Prometheus monitoring
 type Metrics struct {
-	eventsProcessed prometheus.Counter
-	eventsPerSecond prometheus.Gauge
-	processingTime   prometheus.Histogram
-	errorRate       prometheus.Counter
+	eventsProcessed     prometheus.Counter
+	eventsPerSecond     prometheus.Gauge
+	processingTime      prometheus.Histogram
+	errorRate           prometheus.Counter
 	circuitBreakerState prometheus.Gauge
-	connectionPoolSize prometheus.Gauge
-	backpressureQueue prometheus.Gauge
+	connectionPoolSize  prometheus.Gauge
+	backpressureQueue   prometheus.Gauge
+	rateLimitRejections *prometheus.CounterVec
 }
 
-// Protobuf message for optimized serialization
-type ThreatEvent struct {
-	Id          string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
-	Timestamp   *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=timestamp,proto3" json:"timestamp,omitempty"`
-	ThreatType  string                 `protobuf:"bytes,3,opt,name=threat_type,json=threatType,proto3" json:"threat_type,omitempty"`
-	Confidence  float32                `protobuf:"fixed32,4,opt,name=confidence,proto3" json:"confidence,omitempty"`
-	Payload     []byte                 `protobuf:"bytes,5,opt,name=payload,proto3" json:"payload,omitempty"`
-	Metadata    map[string]string      `protobuf:"bytes,6,rep,name=metadata,proto3" json:"metadata,omitempty" protobuf_key:"bytes,1,opt,name=key,proto3" protobuf_val:"bytes,2,opt,name=value,proto3"`
-}
+// ThreatEvent is the canonical threats.v1.ThreatEvent shape; see
+// go-services/threats for its wire encoding and the v0 migration shim.
+type ThreatEvent = threats.ThreatEvent
 
 func NewOptimizedBridge() (*OptimizedBridge, error) {
 	// Initialize connection pool
 	pool := &ConnectionPool{
-		connections: make(chan *nats.Conn, 10),
+		connections:    make(chan *nats.Conn, 10),
 		maxConnections: 10,
-		active: make(map[*nats.Conn]bool),
+		active:         make(map[*nats.Conn]bool),
 	}
 
 	// Initialize circuit breaker
@@ -97,12 +334,6 @@ func NewOptimizedBridge() (*OptimizedBridge, error) {
 		},
 	})
 
-	// Initialize backpressure handler
-	bp := &BackpressureHandler{
-		rateLimiter: make(chan struct{}, 1000), // 1000 events/sec max
-		maxRate:     1000,
-	}
-
 	// Initialize request batcher
 	batcher := &RequestBatcher{
 		batchSize:    100,
@@ -142,6 +373,10 @@ func NewOptimizedBridge() (*OptimizedBridge, error) {
 			Name: "ultra_siem_backpressure_queue_size",
 			Help: "Number of events in backpressure queue",
 		}),
+		rateLimitRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "ultra_siem_rate_limit_rejections_total",
+			Help: "Events rejected by the per-threat-type rate limiter",
+		}, []string{"threat_type"}),
 	}
 
 	// Register metrics
@@ -153,15 +388,17 @@ func NewOptimizedBridge() (*OptimizedBridge, error) {
 		metrics.circuitBreakerState,
 		metrics.connectionPoolSize,
 		metrics.backpressureQueue,
+		metrics.rateLimitRejections,
 	)
 
 	// Connect to NATS with circuit breaker
-	nc, err := cb.Execute(func() (interface{}, error) {
+	connResult, err := cb.Execute(func() (interface{}, error) {
 		return nats.Connect(nats.DefaultURL, nats.MaxReconnects(-1))
 	})
 	if err != nil {
 		return nil, fmt.Errorf("failed to connect to NATS: %v", err)
 	}
+	nc := connResult.(*nats.Conn)
 
 	// Get JetStream context
 	js, err := nc.JetStream()
@@ -169,14 +406,23 @@ func NewOptimizedBridge() (*OptimizedBridge, error) {
 		return nil, fmt.Errorf("failed to get JetStream context: %v", err)
 	}
 
+	// Initialize backpressure handler now that the NATS connection is
+	// available, and let operators retune limits without a restart via
+	// a control subject.
+	bp := NewBackpressureHandler()
+	bp.nc = nc
+	if _, err := nc.Subscribe(rateLimitControlSubject, bp.handleControlMessage); err != nil {
+		return nil, fmt.Errorf("failed to subscribe to rate limit control subject: %v", err)
+	}
+
 	return &OptimizedBridge{
-		nc:              nc.(*nats.Conn),
-		js:              js,
-		circuitBreaker:  cb,
-		connectionPool:  pool,
-		metrics:         metrics,
-		backpressure:    bp,
-		requestBatcher:  batcher,
+		nc:               nc,
+		js:               js,
+		circuitBreaker:   cb,
+		connectionPool:   pool,
+		metrics:          metrics,
+		backpressure:     bp,
+		requestBatcher:   batcher,
 		gracefulShutdown: make(chan struct{}),
 	}, nil
 }
@@ -216,22 +462,41 @@ func (b *OptimizedBridge) handleThreatEvent(msg *nats.Msg) {
 		b.metrics.eventsProcessed.Inc()
 	}()
 
-	// Apply backpressure
-	select {
-	case b.backpressure.rateLimiter <- struct{}{}:
-	default:
-		log.Println("‚ö†Ô∏è Backpressure applied - dropping event")
+	event, err := threats.DecodeMessage(msg.Header, msg.Data)
+	if err != nil {
+		log.Printf("❌ Error decoding event: %v", err)
 		b.metrics.errorRate.Inc()
 		return
 	}
 
+	// Pace per threat type with a real token bucket, rather than just
+	// counting events in a fixed-size channel.
+	waitStart := time.Now()
+	if !b.backpressure.limiterFor(event.ThreatType).Allow() {
+		b.backpressure.recordDrop(event.ThreatType)
+		b.metrics.rateLimitRejections.WithLabelValues(event.ThreatType).Inc()
+		b.backpressure.publishFlowControl(event.ThreatType, "rate_limited")
+		log.Printf("⚠️ Rate limit applied - dropping %s event", event.ThreatType)
+		b.metrics.errorRate.Inc()
+		return
+	}
+	b.backpressure.recordWait(event.ThreatType, time.Since(waitStart))
+
+	// Legacy queue occupancy gauge; once it's over 80% full, tell upstream
+	// to slow down too. monitorBackpressure resets this every second, so
+	// it reflects this second's load rather than latching at 100% forever.
+	atomic.AddInt64(&b.backpressure.queueDepth, 1)
+	if b.backpressure.occupancyRatio() > 0.8 {
+		b.backpressure.publishFlowControl(event.ThreatType, "queue_saturated")
+	}
+
 	// Process with circuit breaker
-	_, err := b.circuitBreaker.Execute(func() (interface{}, error) {
-		return b.processEvent(msg.Data)
+	_, err = b.circuitBreaker.Execute(func() (interface{}, error) {
+		return b.storeEvent(event)
 	})
 
 	if err != nil {
-		log.Printf("‚ùå Error processing event: %v", err)
+		log.Printf("❌ Error processing event: %v", err)
 		b.metrics.errorRate.Inc()
 		return
 	}
@@ -240,23 +505,12 @@ func (b *OptimizedBridge) handleThreatEvent(msg *nats.Msg) {
 	b.updateEventsPerSecond()
 }
 
-func (b *OptimizedBridge) processEvent(data []byte) (interface{}, error) {
-	// Try protobuf first, fallback to JSON
-	var event ThreatEvent
-	
-	// Attempt protobuf deserialization
-	if err := proto.Unmarshal(data, &event); err != nil {
-		// Fallback to JSON
-		if err := json.Unmarshal(data, &event); err != nil {
-			return nil, fmt.Errorf("failed to deserialize event: %v", err)
-		}
-	}
-
+func (b *OptimizedBridge) storeEvent(event *ThreatEvent) (interface{}, error) {
 	// Process the event (simplified for demo)
-	log.Printf("üîç Processing threat: %s (confidence: %.2f)", event.ThreatType, event.Confidence)
+	log.Printf("🔍 Processing threat: %s (confidence: %.2f)", event.ThreatType, event.Confidence)
 
 	// Store in ClickHouse via optimized batch
-	b.requestBatcher.add(event)
+	b.requestBatcher.add(*event)
 
 	return event, nil
 }
@@ -267,10 +521,14 @@ func (b *OptimizedBridge) startMetricsServer() {
 		w.WriteHeader(http.StatusOK)
 		w.Write([]byte("OK"))
 	})
+	http.HandleFunc("/debug/ratelimit", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(b.backpressure.snapshot())
+	})
 
-	log.Println("üìä Starting metrics server on :8080")
+	log.Println("📊 Starting metrics server on :8080")
 	if err := http.ListenAndServe(":8080", nil); err != nil {
-		log.Printf("‚ùå Metrics server error: %v", err)
+		log.Printf("❌ Metrics server error: %v", err)
 	}
 }
 
@@ -281,11 +539,11 @@ func (b *OptimizedBridge) monitorBackpressure() {
 	for {
 		select {
 		case <-ticker.C:
-			queueSize := len(b.backpressure.rateLimiter)
+			queueSize := atomic.SwapInt64(&b.backpressure.queueDepth, 0)
 			b.metrics.backpressureQueue.Set(float64(queueSize))
-			
+
 			if queueSize > 800 {
-				log.Printf("‚ö†Ô∏è High backpressure: %d events in queue", queueSize)
+				log.Printf("⚠️ High backpressure: %d events in queue", queueSize)
 			}
 		case <-b.gracefulShutdown:
 			return
@@ -325,13 +583,13 @@ func (b *OptimizedBridge) updateEventsPerSecond() {
 func (b *OptimizedBridge) Shutdown() {
 	log.Println("üõë Shutting down optimized bridge...")
 	close(b.gracefulShutdown)
-	
+
 	// Wait for all goroutines
 	b.wg.Wait()
-	
+
 	// Close NATS connection
 	b.nc.Close()
-	
+
 	log.Println("‚úÖ Optimized bridge shutdown complete")
 }
 
@@ -346,11 +604,11 @@ func (cp *ConnectionPool) Get() (*nats.Conn, error) {
 		if err != nil {
 			return nil, err
 		}
-		
+
 		cp.mu.Lock()
 		cp.active[conn] = true
 		cp.mu.Unlock()
-		
+
 		return conn, nil
 	}
 }
@@ -362,7 +620,7 @@ func (cp *ConnectionPool) Put(conn *nats.Conn) {
 	default:
 		// Pool is full, close connection
 		conn.Close()
-		
+
 		cp.mu.Lock()
 		delete(cp.active, conn)
 		cp.mu.Unlock()
@@ -385,7 +643,7 @@ func (rb *RequestBatcher) start() {
 		select {
 		case item := <-rb.batches:
 			batch = append(batch, item...)
-			
+
 			if len(batch) >= rb.batchSize {
 				rb.processBatch(batch)
 				batch = batch[:0]
@@ -423,4 +681,4 @@ func main() {
 
 	// Wait for shutdown signal
 	select {}
-} 
\ No newline at end of file
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"runtime"
+)
+
+// Reader replays a frame stream produced by writerSink back into events,
+// so a file captured via SINK_FILE_PATH can be reprocessed offline by
+// feeding it back into a RingBuffer.
+type Reader struct {
+	r io.Reader
+}
+
+// NewReader wraps r for frame-by-frame replay.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: r}
+}
+
+// Next reads and decodes the next frame, returning io.EOF once the stream
+// is exhausted on a clean frame boundary.
+func (r *Reader) Next() (*ThreatEvent, error) {
+	var length [4]byte
+	if _, err := io.ReadFull(r.r, length[:]); err != nil {
+		if err == io.ErrUnexpectedEOF {
+			return nil, fmt.Errorf("reading frame length: %w", err)
+		}
+		return nil, err
+	}
+
+	payload := make([]byte, binary.BigEndian.Uint32(length[:]))
+	if _, err := io.ReadFull(r.r, payload); err != nil {
+		return nil, fmt.Errorf("reading frame payload: %w", err)
+	}
+
+	var event ThreatEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("unmarshaling event: %w", err)
+	}
+	return &event, nil
+}
+
+// Replay reads every frame from r and Puts each into buf, stopping at the
+// first error other than io.EOF. It returns the number of events replayed.
+func (r *Reader) Replay(buf *RingBuffer) (int, error) {
+	n := 0
+	for {
+		event, err := r.Next()
+		if err == io.EOF {
+			return n, nil
+		}
+		if err != nil {
+			return n, err
+		}
+		for !buf.Put(event) {
+			runtime.Gosched()
+		}
+		n++
+	}
+}
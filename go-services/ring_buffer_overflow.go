@@ -0,0 +1,274 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// OverflowPolicy selects what RingBuffer.Put does when the buffer is full.
+type OverflowPolicy int
+
+const (
+	// DropNewest discards the incoming event, leaving the buffer
+	// unchanged. This is RingBuffer's original (and still default)
+	// behavior, appropriate when recent events matter more than complete
+	// history and a slow consumer shouldn't apply backpressure upstream.
+	DropNewest OverflowPolicy = iota
+	// DropOldest evicts the oldest buffered event to make room for the
+	// incoming one, favoring recency over completeness — most useful
+	// under a sustained flood (e.g. a DoS) where the newest events are
+	// the ones worth keeping.
+	DropOldest
+	// BlockWithTimeout blocks Put for up to RingBufferOptions.BlockTimeout
+	// waiting for a consumer to free a slot, returning false if none
+	// opens up in time. Useful when producers can tolerate backpressure
+	// and dropping is worse than a bounded stall.
+	BlockWithTimeout
+	// SpillToDisk appends the overflowing event to an on-disk segment
+	// file (RingBufferOptions.SpillPath) instead of dropping it. Spilled
+	// events are reclaimed back into the ring as capacity frees up, and
+	// any left in the segment from a previous process are replayed in at
+	// startup (see spillSegment).
+	SpillToDisk
+)
+
+// RingBufferOptions configures NewRingBuffer's overflow behavior. The zero
+// value selects DropNewest, matching RingBuffer's original behavior.
+type RingBufferOptions struct {
+	Policy OverflowPolicy
+	// BlockTimeout is how long Put waits for room under BlockWithTimeout.
+	// Ignored by every other policy.
+	BlockTimeout time.Duration
+	// SpillPath is the on-disk segment file Put overflows into under
+	// SpillToDisk. Ignored by every other policy.
+	SpillPath string
+}
+
+// putDropOldest is OverflowPolicy DropOldest's Put: when the buffer is
+// full it evicts the oldest buffered event (advancing head) before
+// appending, so the incoming event always gets a slot. Unlike the
+// lock-free default path, this holds rb.mu for the whole operation since
+// evicting and appending must happen as one step; head/tail are still
+// only ever touched via sync/atomic, so this never races with TryGet's
+// lock-free reads.
+func (rb *RingBuffer) putDropOldest(event *ThreatEvent) bool {
+	rb.mu.Lock()
+	defer rb.mu.Unlock()
+
+	tail := atomic.LoadUint64(&rb.tail)
+	next := (tail + 1) & rb.mask
+
+	if next == atomic.LoadUint64(&rb.head) {
+		head := atomic.LoadUint64(&rb.head)
+		rb.buffer[head] = nil
+		atomic.StoreUint64(&rb.head, (head+1)&rb.mask)
+	}
+
+	rb.buffer[tail] = event
+	atomic.StoreUint64(&rb.tail, next)
+	rb.notEmpty.Broadcast()
+	return true
+}
+
+// putBlockWithTimeout is OverflowPolicy BlockWithTimeout's Put: it retries
+// tryPutOnce, parking on notFull (woken by every successful TryGet)
+// between attempts, until it succeeds or rb.blockTimeout elapses.
+func (rb *RingBuffer) putBlockWithTimeout(event *ThreatEvent) bool {
+	deadline := time.Now().Add(rb.blockTimeout)
+
+	for {
+		if rb.tryPutOnce(event) {
+			return true
+		}
+
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return false
+		}
+
+		rb.mu.Lock()
+		timer := time.AfterFunc(remaining, func() {
+			rb.mu.Lock()
+			rb.notFull.Broadcast()
+			rb.mu.Unlock()
+		})
+		rb.notFull.Wait()
+		timer.Stop()
+		rb.mu.Unlock()
+	}
+}
+
+// putSpillToDisk is OverflowPolicy SpillToDisk's Put: it falls back to
+// durably appending the event to rb.spill instead of dropping it when the
+// ring has no room. TryGet reclaims spilled events back into the ring as
+// slots free up.
+func (rb *RingBuffer) putSpillToDisk(event *ThreatEvent) bool {
+	if rb.tryPutOnce(event) {
+		return true
+	}
+	if err := rb.spill.append(event); err != nil {
+		log.Printf("ring buffer: failed to spill overflow event to disk: %v", err)
+		return false
+	}
+	return true
+}
+
+// reclaimFromSpill pulls one event back from rb.spill, if any is waiting,
+// into the slot TryGet just freed. Called from TryGet; a no-op unless
+// rb.spill is set (OverflowPolicy SpillToDisk).
+func (rb *RingBuffer) reclaimFromSpill() {
+	event, err := rb.spill.next()
+	if err != nil {
+		log.Printf("ring buffer: failed to reclaim spilled event: %v", err)
+		return
+	}
+	if event == nil {
+		return
+	}
+	if !rb.putDropNewest(event) {
+		// Lost the race for the slot that was just freed; put it back
+		// rather than lose it.
+		if err := rb.spill.append(event); err != nil {
+			log.Printf("ring buffer: failed to re-spill event after losing slot race: %v", err)
+		}
+	}
+}
+
+// recoverSpill replays events left over in rb.spill's segment file by a
+// previous process (one that exited before fully draining it) back into
+// the ring, up to capacity. Called once from NewRingBuffer; anything that
+// doesn't fit stays in the segment to be reclaimed later, same as any
+// other spilled event.
+func (rb *RingBuffer) recoverSpill() {
+	for rb.Occupancy() < float64(rb.mask)/float64(rb.mask+1) {
+		event, err := rb.spill.next()
+		if err != nil {
+			log.Printf("ring buffer: spill segment recovery error: %v", err)
+			return
+		}
+		if event == nil {
+			return
+		}
+		if !rb.putDropNewest(event) {
+			return
+		}
+	}
+}
+
+// spillSegment is RingBuffer's on-disk overflow store for OverflowPolicy
+// SpillToDisk: events that don't fit in the ring are appended here as
+// length-prefixed frames (the same framing writer_sink.go uses) instead of
+// being dropped, and reclaimed back into the ring as capacity frees up.
+//
+// The file is opened O_APPEND, so appends always land at EOF regardless of
+// readPos, letting append and next share one *os.File without their seeks
+// interfering with each other.
+type spillSegment struct {
+	mu      sync.Mutex
+	file    *os.File
+	writer  *writerSink
+	readPos int64 // next unreclaimed frame's offset
+	pending int   // frames appended but not yet reclaimed
+}
+
+// newSpillSegment opens (creating if necessary) the segment file at path
+// and counts any frames already in it, so a process that crashed before
+// draining its segment doesn't lose them — recoverSpill replays them back
+// into the ring afterward.
+func newSpillSegment(path string) (*spillSegment, error) {
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("opening spill segment %s: %w", path, err)
+	}
+
+	s := &spillSegment{file: f, writer: newWriterSink(f)}
+	if err := s.countExistingFrames(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+// countExistingFrames scans the segment file end to end to learn how many
+// frames are already in it (left over from a previous process), without
+// disturbing readPos, which stays at its zero value so next() re-reads
+// them from the start.
+func (s *spillSegment) countExistingFrames() error {
+	if _, err := s.file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+
+	reader := NewReader(s.file)
+	count := 0
+	for {
+		if _, err := reader.Next(); err != nil {
+			if err == io.EOF {
+				break
+			}
+			return fmt.Errorf("scanning spill segment: %w", err)
+		}
+		count++
+	}
+	s.pending = count
+	return nil
+}
+
+// append serializes event onto the end of the segment file.
+func (s *spillSegment) append(event *ThreatEvent) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := s.writer.Write(event); err != nil {
+		return err
+	}
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	s.pending++
+	return nil
+}
+
+// next reads the oldest not-yet-reclaimed frame, advancing readPos past
+// it. It returns (nil, nil), not an error, once every appended frame has
+// been reclaimed.
+func (s *spillSegment) next() (*ThreatEvent, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.pending == 0 {
+		return nil, nil
+	}
+
+	if _, err := s.file.Seek(s.readPos, io.SeekStart); err != nil {
+		return nil, fmt.Errorf("seeking spill segment: %w", err)
+	}
+
+	event, err := NewReader(s.file).Next()
+	if err != nil {
+		if err == io.EOF {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("reading spill segment: %w", err)
+	}
+
+	pos, err := s.file.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return nil, fmt.Errorf("tracking spill segment read position: %w", err)
+	}
+	s.readPos = pos
+	s.pending--
+	return event, nil
+}
+
+// close closes the segment file. It does not delete it: any frames still
+// pending remain on disk for the next process to recover.
+func (s *spillSegment) close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.file.Close()
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestRingBufferDropOldestEvictsOldest tests that DropOldest makes room by
+// evicting the oldest buffered event instead of dropping the incoming one.
+func TestRingBufferDropOldestEvictsOldest(t *testing.T) {
+	buffer := NewRingBuffer(4, RingBufferOptions{Policy: DropOldest})
+
+	events := []*ThreatEvent{
+		{SourceIP: "1.1.1.1"},
+		{SourceIP: "2.2.2.2"},
+		{SourceIP: "3.3.3.3"},
+	}
+	for _, e := range events {
+		if !buffer.Put(e) {
+			t.Fatalf("expected Put(%s) to succeed", e.SourceIP)
+		}
+	}
+
+	overflow := &ThreatEvent{SourceIP: "4.4.4.4"}
+	if !buffer.Put(overflow) {
+		t.Fatal("expected DropOldest Put to succeed even when full")
+	}
+
+	for _, want := range []string{"2.2.2.2", "3.3.3.3", "4.4.4.4"} {
+		got, ok := buffer.TryGet()
+		if !ok {
+			t.Fatalf("expected event %q, buffer was empty", want)
+		}
+		if got.SourceIP != want {
+			t.Errorf("expected %s, got %s", want, got.SourceIP)
+		}
+	}
+}
+
+// TestRingBufferBlockWithTimeoutSucceedsWhenSpaceFrees tests that a
+// blocked Put returns true once a concurrent TryGet frees a slot.
+func TestRingBufferBlockWithTimeoutSucceedsWhenSpaceFrees(t *testing.T) {
+	buffer := NewRingBuffer(2, RingBufferOptions{Policy: BlockWithTimeout, BlockTimeout: time.Second})
+
+	if !buffer.Put(&ThreatEvent{SourceIP: "1.1.1.1"}) {
+		t.Fatal("expected first Put to succeed")
+	}
+
+	done := make(chan bool, 1)
+	go func() {
+		done <- buffer.Put(&ThreatEvent{SourceIP: "2.2.2.2"})
+	}()
+
+	time.Sleep(50 * time.Millisecond)
+	if _, ok := buffer.TryGet(); !ok {
+		t.Fatal("expected to free a slot for the blocked Put")
+	}
+
+	select {
+	case ok := <-done:
+		if !ok {
+			t.Error("expected blocked Put to succeed once a slot freed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for blocked Put to return")
+	}
+}
+
+// TestRingBufferBlockWithTimeoutTimesOut tests that Put gives up and
+// returns false once BlockTimeout elapses with no room.
+func TestRingBufferBlockWithTimeoutTimesOut(t *testing.T) {
+	buffer := NewRingBuffer(2, RingBufferOptions{Policy: BlockWithTimeout, BlockTimeout: 50 * time.Millisecond})
+
+	if !buffer.Put(&ThreatEvent{SourceIP: "1.1.1.1"}) {
+		t.Fatal("expected first Put to succeed")
+	}
+
+	start := time.Now()
+	if buffer.Put(&ThreatEvent{SourceIP: "2.2.2.2"}) {
+		t.Fatal("expected Put to fail once the timeout elapses with no room")
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond {
+		t.Errorf("expected Put to wait out its timeout, returned after %v", elapsed)
+	}
+}
+
+// TestRingBufferSpillToDiskNoEventLoss tests that overflow events spilled
+// to disk are reclaimed back into the ring, in order, as slots free up.
+func TestRingBufferSpillToDiskNoEventLoss(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overflow.seg")
+	buffer := NewRingBuffer(2, RingBufferOptions{Policy: SpillToDisk, SpillPath: path})
+	defer buffer.Close()
+
+	events := []*ThreatEvent{
+		{SourceIP: "1.1.1.1"}, // fits in the ring
+		{SourceIP: "2.2.2.2"}, // overflow: spilled to disk
+		{SourceIP: "3.3.3.3"}, // overflow: spilled to disk
+	}
+	for _, e := range events {
+		if !buffer.Put(e) {
+			t.Fatalf("expected Put(%s) to be accepted (buffered or spilled)", e.SourceIP)
+		}
+	}
+
+	for _, want := range []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"} {
+		got, ok := buffer.TryGet()
+		if !ok {
+			t.Fatalf("expected event %q, buffer was empty", want)
+		}
+		if got.SourceIP != want {
+			t.Errorf("expected %s, got %s", want, got.SourceIP)
+		}
+	}
+}
+
+// TestRingBufferSpillToDiskRecoversAcrossRestart tests that events left in
+// the segment file by a process that exited before draining it are
+// replayed back in when a new RingBuffer opens the same segment.
+func TestRingBufferSpillToDiskRecoversAcrossRestart(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "overflow.seg")
+
+	buffer := NewRingBuffer(2, RingBufferOptions{Policy: SpillToDisk, SpillPath: path})
+	if !buffer.Put(&ThreatEvent{SourceIP: "1.1.1.1"}) {
+		t.Fatal("expected first Put to succeed")
+	}
+	if !buffer.Put(&ThreatEvent{SourceIP: "2.2.2.2"}) {
+		t.Fatal("expected overflow Put to be spilled")
+	}
+	if !buffer.Put(&ThreatEvent{SourceIP: "3.3.3.3"}) {
+		t.Fatal("expected overflow Put to be spilled")
+	}
+	// Simulate a crash: close without draining, so "2.2.2.2" and
+	// "3.3.3.3" are still sitting in the segment file ("1.1.1.1" was only
+	// ever in the in-memory ring and is lost, matching a real crash).
+	if err := buffer.Close(); err != nil {
+		t.Fatalf("Close returned error: %v", err)
+	}
+
+	restarted := NewRingBuffer(2, RingBufferOptions{Policy: SpillToDisk, SpillPath: path})
+	defer restarted.Close()
+
+	for _, want := range []string{"2.2.2.2", "3.3.3.3"} {
+		got, ok := restarted.TryGet()
+		if !ok {
+			t.Fatalf("expected recovered event %q, buffer was empty", want)
+		}
+		if got.SourceIP != want {
+			t.Errorf("recovered event: got %s, want %s", got.SourceIP, want)
+		}
+	}
+}
+
+// BenchmarkRingBufferPutDropOldest measures DropOldest's Put at
+// saturation (every call past the first 1023 evicts the oldest event).
+func BenchmarkRingBufferPutDropOldest(b *testing.B) {
+	buffer := NewRingBuffer(1024, RingBufferOptions{Policy: DropOldest})
+	event := &ThreatEvent{SourceIP: "192.168.1.1", ThreatType: "benchmark"}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buffer.Put(event)
+	}
+}
+
+// BenchmarkRingBufferPutBlockWithTimeout measures BlockWithTimeout's Put
+// at saturation, with a background goroutine draining just fast enough to
+// keep producers from actually hitting their timeout.
+func BenchmarkRingBufferPutBlockWithTimeout(b *testing.B) {
+	buffer := NewRingBuffer(1024, RingBufferOptions{Policy: BlockWithTimeout, BlockTimeout: time.Second})
+	event := &ThreatEvent{SourceIP: "192.168.1.1", ThreatType: "benchmark"}
+
+	stop := make(chan struct{})
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				buffer.TryGet()
+			}
+		}
+	}()
+	defer close(stop)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buffer.Put(event)
+	}
+}
+
+// BenchmarkRingBufferPutSpillToDisk measures SpillToDisk's Put once the
+// ring is pre-filled, so every call in the timed loop spills to disk.
+func BenchmarkRingBufferPutSpillToDisk(b *testing.B) {
+	path := filepath.Join(b.TempDir(), "overflow.seg")
+	buffer := NewRingBuffer(1024, RingBufferOptions{Policy: SpillToDisk, SpillPath: path})
+	defer buffer.Close()
+	event := &ThreatEvent{SourceIP: "192.168.1.1", ThreatType: "benchmark"}
+
+	for i := 0; i < 1024; i++ {
+		buffer.Put(event)
+	}
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		buffer.Put(event)
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"os"
+)
+
+// Sink is the destination interface for processed threat events. Each
+// registered sink receives every event the processor accepts, independent
+// of how many other sinks are configured: ClickHouse remains the
+// durability backstop (retries, circuit breaker, DLQ), while lighter
+// sinks (audit file, stdout) exist for replay, auditing, and debugging.
+type Sink interface {
+	// Write hands a single event to the sink. Implementations that want to
+	// batch (e.g. ClickHouse inserts) should buffer internally and do the
+	// actual write in Flush, not on every call.
+	Write(event *ThreatEvent) error
+	// Flush forces any buffered events out to the underlying destination.
+	Flush() error
+	// Close releases any resources held by the sink. Close does not imply
+	// Flush; callers must Flush first if pending data must not be lost.
+	Close() error
+}
+
+// MultiSink fans every event out to all of its member sinks, so a single
+// processing pipeline can write to ClickHouse, a local audit file, and
+// stdout at once without the caller juggling multiple Sink references.
+//
+// Write/Flush/Close all fan out to every member sink even after one of
+// them fails, so one broken sink (e.g. a full disk) doesn't stop events
+// from reaching the others; errors from every member are joined together.
+type MultiSink struct {
+	sinks []Sink
+}
+
+// NewMultiSink returns a MultiSink fanning out to sinks, in the order given.
+func NewMultiSink(sinks ...Sink) *MultiSink {
+	return &MultiSink{sinks: sinks}
+}
+
+func (m *MultiSink) Write(event *ThreatEvent) error {
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.Write(event); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MultiSink) Flush() error {
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.Flush(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *MultiSink) Close() error {
+	var errs []error
+	for _, s := range m.sinks {
+		if err := s.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// buildSink assembles the processor's Sink from environment configuration.
+// ClickHouse is always included (it owns the retry/circuit-breaker/DLQ
+// handling writeBatch already implements); SINK_FILE_PATH and
+// SINK_STDOUT_LOG optionally add an audit file and/or a console mirror
+// alongside it.
+//
+// Configuration:
+// - SINK_FILE_PATH: if set, append length-prefixed event frames to this file
+// - SINK_STDOUT_LOG: "true" to also log every event via the standard logger
+func buildSink(sp *SIEMProcessor) (Sink, error) {
+	sinks := []Sink{newClickHouseSink(sp.writeBatch)}
+
+	if path := getEnv("SINK_FILE_PATH", ""); path != "" {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+		if err != nil {
+			return nil, fmt.Errorf("opening sink file %s: %w", path, err)
+		}
+		sinks = append(sinks, newWriterSink(f))
+	}
+
+	if getEnv("SINK_STDOUT_LOG", "false") == "true" {
+		sinks = append(sinks, newLogSink(log.Default()))
+	}
+
+	return NewMultiSink(sinks...), nil
+}
@@ -0,0 +1,39 @@
+package threats
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// legacyV0Event mirrors the flat ThreatEvent JSON shape the processor and
+// optimized bridge spoke before the threats.v1 schema existed: a Unix
+// timestamp instead of google.protobuf.Timestamp, a string Payload instead
+// of bytes, and no Id or Metadata.
+type legacyV0Event struct {
+	Timestamp  uint64  `json:"timestamp"`
+	SourceIP   string  `json:"source_ip"`
+	ThreatType string  `json:"threat_type"`
+	Payload    string  `json:"payload"`
+	Severity   uint8   `json:"severity"`
+	Confidence float32 `json:"confidence"`
+}
+
+// migrateV0 upconverts a legacy v0 JSON event into the canonical
+// threats.v1.ThreatEvent shape, so old producers can keep sending their
+// existing payload unchanged while v1 rolls out.
+func migrateV0(data []byte) (*ThreatEvent, error) {
+	var legacy legacyV0Event
+	if err := json.Unmarshal(data, &legacy); err != nil {
+		return nil, fmt.Errorf("threats: migrate v0: %w", err)
+	}
+
+	return &ThreatEvent{
+		Timestamp:  time.Unix(int64(legacy.Timestamp), 0).UTC(),
+		SourceIP:   legacy.SourceIP,
+		ThreatType: legacy.ThreatType,
+		Payload:    []byte(legacy.Payload),
+		Severity:   legacy.Severity,
+		Confidence: legacy.Confidence,
+	}, nil
+}
@@ -0,0 +1,83 @@
+package threats
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Decoder turns a raw message body into a canonical ThreatEvent. Registered
+// decoders are looked up by schema ID so a producer can keep sending an old
+// shape (tagged with its schema ID) indefinitely, while new producers move
+// straight to SchemaV1.
+type Decoder func(data []byte) (*ThreatEvent, error)
+
+// registry maps schema ID to the decoder that understands it. Seeded with
+// the v1 and v0 decoders below; not exported since callers should go
+// through DecodeMessage.
+var registry = map[string]Decoder{
+	SchemaV1: decodeV1,
+	SchemaV0: migrateV0,
+}
+
+// Register adds or replaces the decoder for schemaID, so a future schema
+// version can be wired in without changing DecodeMessage's dispatch logic.
+func Register(schemaID string, dec Decoder) {
+	registry[schemaID] = dec
+}
+
+// Header is the minimal view of a NATS message DecodeMessage needs: just
+// enough to read Content-Type and Schema-Id without importing nats.go here
+// and coupling this package to a transport.
+type Header interface {
+	Get(key string) string
+}
+
+// DecodeMessage picks a decoder using the message's Schema-Id header
+// (defaulting to SchemaV0 for producers that predate this registry) and
+// decodes data with it. The Content-Type header is honored only for
+// SchemaV1: "+proto" uses Unmarshal, anything else (including a missing
+// header) falls back to JSON, since legacy v0 producers only ever spoke
+// JSON.
+func DecodeMessage(header Header, data []byte) (*ThreatEvent, error) {
+	schemaID := header.Get(SchemaIDHeader)
+	if schemaID == "" {
+		schemaID = SchemaV0
+	}
+
+	if schemaID == SchemaV1 && header.Get(ContentTypeHeader) == ContentTypeProto {
+		var event ThreatEvent
+		if err := Unmarshal(data, &event); err != nil {
+			return nil, fmt.Errorf("threats: decode proto v1: %w", err)
+		}
+		return &event, nil
+	}
+
+	dec, ok := registry[schemaID]
+	if !ok {
+		return nil, fmt.Errorf("threats: unknown schema id %q", schemaID)
+	}
+	return dec(data)
+}
+
+func decodeV1(data []byte) (*ThreatEvent, error) {
+	var event ThreatEvent
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, fmt.Errorf("threats: decode json v1: %w", err)
+	}
+	return &event, nil
+}
+
+// Validate reports whether e satisfies the minimal invariants the rest of
+// the pipeline assumes: a non-empty ThreatType (used for rate limiting and
+// ClickHouse partitioning) and a non-zero Timestamp. Intended for replay
+// paths (e.g. a dead-letter queue) that re-check an event against the
+// current schema before re-inserting it.
+func Validate(e *ThreatEvent) error {
+	if e.ThreatType == "" {
+		return fmt.Errorf("threats: missing threat_type")
+	}
+	if e.Timestamp.IsZero() {
+		return fmt.Errorf("threats: missing timestamp")
+	}
+	return nil
+}
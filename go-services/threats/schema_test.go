@@ -0,0 +1,73 @@
+package threats
+
+import (
+	"testing"
+	"time"
+)
+
+type fakeHeader map[string]string
+
+func (h fakeHeader) Get(key string) string { return h[key] }
+
+func TestDecodeMessageDefaultsToV0(t *testing.T) {
+	event, err := DecodeMessage(fakeHeader{}, []byte(`{"source_ip":"1.2.3.4","threat_type":"sql_injection","timestamp":1700000000}`))
+	if err != nil {
+		t.Fatalf("DecodeMessage failed: %v", err)
+	}
+	if event.ThreatType != "sql_injection" {
+		t.Errorf("ThreatType: got %q, want %q", event.ThreatType, "sql_injection")
+	}
+	if event.Timestamp.Unix() != 1700000000 {
+		t.Errorf("Timestamp: got %v, want unix 1700000000", event.Timestamp)
+	}
+}
+
+func TestDecodeMessageV1JSON(t *testing.T) {
+	header := fakeHeader{SchemaIDHeader: SchemaV1}
+	event, err := DecodeMessage(header, []byte(`{"id":"evt-2","threat_type":"malware"}`))
+	if err != nil {
+		t.Fatalf("DecodeMessage failed: %v", err)
+	}
+	if event.Id != "evt-2" {
+		t.Errorf("Id: got %q, want %q", event.Id, "evt-2")
+	}
+}
+
+func TestDecodeMessageV1Proto(t *testing.T) {
+	want := &ThreatEvent{Id: "evt-3", ThreatType: "ddos"}
+	data, err := Marshal(want)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	header := fakeHeader{SchemaIDHeader: SchemaV1, ContentTypeHeader: ContentTypeProto}
+	got, err := DecodeMessage(header, data)
+	if err != nil {
+		t.Fatalf("DecodeMessage failed: %v", err)
+	}
+	if got.Id != want.Id || got.ThreatType != want.ThreatType {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+}
+
+func TestDecodeMessageUnknownSchema(t *testing.T) {
+	header := fakeHeader{SchemaIDHeader: "v99"}
+	if _, err := DecodeMessage(header, nil); err == nil {
+		t.Error("expected an error for an unregistered schema id")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := &ThreatEvent{ThreatType: "malware", Timestamp: time.Unix(1700000000, 0)}
+	if err := Validate(valid); err != nil {
+		t.Errorf("expected valid event to pass, got: %v", err)
+	}
+
+	if err := Validate(&ThreatEvent{Timestamp: time.Unix(1700000000, 0)}); err == nil {
+		t.Error("expected missing ThreatType to fail validation")
+	}
+
+	if err := Validate(&ThreatEvent{ThreatType: "malware"}); err == nil {
+		t.Error("expected missing Timestamp to fail validation")
+	}
+}
@@ -0,0 +1,47 @@
+// Package threats defines the canonical threats.v1.ThreatEvent wire format
+// shared by the processor, bridge, and optimized bridge, plus the schema
+// registry that lets older producers keep sending a legacy shape while v1
+// rolls out.
+//
+// The .proto source of truth lives at proto/threats/v1/threat_event.proto.
+// This package hand-implements its wire encoding in wire.go rather than
+// depending on generated code, since protoc isn't part of this repo's
+// build; field numbers in the struct tags below must stay in sync with the
+// .proto file.
+package threats
+
+import "time"
+
+// Content-Type header values used to pick a decoder for an incoming NATS
+// message.
+const (
+	ContentTypeProto = "application/vnd.ultrasiem.threat+proto"
+	ContentTypeJSON  = "application/vnd.ultrasiem.threat+json"
+)
+
+// ContentTypeHeader and SchemaIDHeader name the NATS headers DecodeMessage
+// inspects to pick a wire format and a schema migration.
+const (
+	ContentTypeHeader = "Content-Type"
+	SchemaIDHeader    = "Schema-Id"
+)
+
+// Schema IDs recognized by the registry in schema.go.
+const (
+	SchemaV0 = "v0" // legacy flat JSON ThreatEvent: no Id, Metadata, or typed Timestamp
+	SchemaV1 = "v1" // canonical threats.v1.ThreatEvent
+)
+
+// ThreatEvent is the canonical threats.v1.ThreatEvent shape. The
+// `protobuf` tag on each field records its wire field number, the same
+// number Marshal/Unmarshal in wire.go hard-code for it.
+type ThreatEvent struct {
+	Id         string            `protobuf:"1" json:"id,omitempty"`
+	Timestamp  time.Time         `protobuf:"2" json:"timestamp"`
+	SourceIP   string            `protobuf:"3" json:"source_ip"`
+	ThreatType string            `protobuf:"4" json:"threat_type"`
+	Payload    []byte            `protobuf:"5" json:"payload,omitempty"`
+	Severity   uint8             `protobuf:"6" json:"severity"`
+	Confidence float32           `protobuf:"7" json:"confidence"`
+	Metadata   map[string]string `protobuf:"8" json:"metadata,omitempty"`
+}
@@ -0,0 +1,225 @@
+package threats
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Wire types from the protobuf encoding spec. ThreatEvent only ever needs
+// varint, 32-bit, and length-delimited.
+const (
+	wireVarint  = 0
+	wireFixed32 = 5
+	wireBytes   = 2
+)
+
+// Marshal encodes e in the wire format described by
+// proto/threats/v1/threat_event.proto. Zero-valued fields are omitted, same
+// as proto3's default field presence rules.
+func Marshal(e *ThreatEvent) ([]byte, error) {
+	buf := make([]byte, 0, 128)
+
+	if e.Id != "" {
+		buf = appendTagBytes(buf, 1, []byte(e.Id))
+	}
+	if !e.Timestamp.IsZero() {
+		buf = appendTagBytes(buf, 2, marshalTimestamp(e.Timestamp))
+	}
+	if e.SourceIP != "" {
+		buf = appendTagBytes(buf, 3, []byte(e.SourceIP))
+	}
+	if e.ThreatType != "" {
+		buf = appendTagBytes(buf, 4, []byte(e.ThreatType))
+	}
+	if len(e.Payload) > 0 {
+		buf = appendTagBytes(buf, 5, e.Payload)
+	}
+	if e.Severity != 0 {
+		buf = appendTagVarint(buf, 6, uint64(e.Severity))
+	}
+	if e.Confidence != 0 {
+		buf = appendTagFixed32(buf, 7, math.Float32bits(e.Confidence))
+	}
+	for k, v := range e.Metadata {
+		buf = appendTagBytes(buf, 8, marshalMapEntry(k, v))
+	}
+
+	return buf, nil
+}
+
+// Unmarshal decodes data produced by Marshal into e. Unknown field numbers
+// are skipped, matching proto3 forward-compatibility.
+func Unmarshal(data []byte, e *ThreatEvent) error {
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return fmt.Errorf("threats: malformed tag")
+		}
+		data = data[n:]
+
+		field := tag >> 3
+		wireType := tag & 0x7
+
+		switch wireType {
+		case wireVarint:
+			v, n := binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("threats: malformed varint for field %d", field)
+			}
+			data = data[n:]
+			if field == 6 {
+				e.Severity = uint8(v)
+			}
+
+		case wireFixed32:
+			if len(data) < 4 {
+				return fmt.Errorf("threats: truncated fixed32 for field %d", field)
+			}
+			v := binary.LittleEndian.Uint32(data)
+			data = data[4:]
+			if field == 7 {
+				e.Confidence = math.Float32frombits(v)
+			}
+
+		case wireBytes:
+			length, n := binary.Uvarint(data)
+			if n <= 0 {
+				return fmt.Errorf("threats: malformed length for field %d", field)
+			}
+			data = data[n:]
+			if uint64(len(data)) < length {
+				return fmt.Errorf("threats: truncated bytes for field %d", field)
+			}
+			value := data[:length]
+			data = data[length:]
+
+			switch field {
+			case 1:
+				e.Id = string(value)
+			case 2:
+				ts, err := unmarshalTimestamp(value)
+				if err != nil {
+					return err
+				}
+				e.Timestamp = ts
+			case 3:
+				e.SourceIP = string(value)
+			case 4:
+				e.ThreatType = string(value)
+			case 5:
+				e.Payload = append([]byte(nil), value...)
+			case 8:
+				k, v, err := unmarshalMapEntry(value)
+				if err != nil {
+					return err
+				}
+				if e.Metadata == nil {
+					e.Metadata = make(map[string]string)
+				}
+				e.Metadata[k] = v
+			}
+
+		default:
+			return fmt.Errorf("threats: unsupported wire type %d for field %d", wireType, field)
+		}
+	}
+
+	return nil
+}
+
+func appendTagVarint(buf []byte, field int, v uint64) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|wireVarint)
+	return binary.AppendUvarint(buf, v)
+}
+
+func appendTagFixed32(buf []byte, field int, v uint32) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|wireFixed32)
+	return binary.LittleEndian.AppendUint32(buf, v)
+}
+
+func appendTagBytes(buf []byte, field int, v []byte) []byte {
+	buf = binary.AppendUvarint(buf, uint64(field)<<3|wireBytes)
+	buf = binary.AppendUvarint(buf, uint64(len(v)))
+	return append(buf, v...)
+}
+
+// marshalTimestamp encodes t the same way google.protobuf.Timestamp would:
+// an embedded message with seconds (field 1, varint) and nanos (field 2,
+// varint), so an actual protoc-generated reader decodes it correctly.
+func marshalTimestamp(t time.Time) []byte {
+	var buf []byte
+	buf = appendTagVarint(buf, 1, uint64(t.Unix()))
+	if n := t.Nanosecond(); n != 0 {
+		buf = appendTagVarint(buf, 2, uint64(n))
+	}
+	return buf
+}
+
+func unmarshalTimestamp(data []byte) (time.Time, error) {
+	var seconds int64
+	var nanos int64
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return time.Time{}, fmt.Errorf("threats: malformed timestamp tag")
+		}
+		data = data[n:]
+		field := tag >> 3
+
+		v, n := binary.Uvarint(data)
+		if n <= 0 {
+			return time.Time{}, fmt.Errorf("threats: malformed timestamp field %d", field)
+		}
+		data = data[n:]
+
+		switch field {
+		case 1:
+			seconds = int64(v)
+		case 2:
+			nanos = int64(v)
+		}
+	}
+	return time.Unix(seconds, nanos).UTC(), nil
+}
+
+// marshalMapEntry encodes a single metadata entry as a MapEntry submessage:
+// field 1 is the key, field 2 is the value, both strings.
+func marshalMapEntry(k, v string) []byte {
+	var buf []byte
+	buf = appendTagBytes(buf, 1, []byte(k))
+	buf = appendTagBytes(buf, 2, []byte(v))
+	return buf
+}
+
+func unmarshalMapEntry(data []byte) (string, string, error) {
+	var k, v string
+	for len(data) > 0 {
+		tag, n := binary.Uvarint(data)
+		if n <= 0 {
+			return "", "", fmt.Errorf("threats: malformed map entry tag")
+		}
+		data = data[n:]
+		field := tag >> 3
+
+		length, n := binary.Uvarint(data)
+		if n <= 0 {
+			return "", "", fmt.Errorf("threats: malformed map entry length")
+		}
+		data = data[n:]
+		if uint64(len(data)) < length {
+			return "", "", fmt.Errorf("threats: truncated map entry")
+		}
+		value := data[:length]
+		data = data[length:]
+
+		switch field {
+		case 1:
+			k = string(value)
+		case 2:
+			v = string(value)
+		}
+	}
+	return k, v, nil
+}
@@ -0,0 +1,70 @@
+package threats
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMarshalUnmarshalRoundTrip(t *testing.T) {
+	original := &ThreatEvent{
+		Id:         "evt-1",
+		Timestamp:  time.Unix(1700000000, 123000000).UTC(),
+		SourceIP:   "10.0.0.1",
+		ThreatType: "malware",
+		Payload:    []byte("suspicious payload"),
+		Severity:   7,
+		Confidence: 0.92,
+		Metadata:   map[string]string{"rule": "yara-42"},
+	}
+
+	data, err := Marshal(original)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded ThreatEvent
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if decoded.Id != original.Id {
+		t.Errorf("Id: got %q, want %q", decoded.Id, original.Id)
+	}
+	if !decoded.Timestamp.Equal(original.Timestamp) {
+		t.Errorf("Timestamp: got %v, want %v", decoded.Timestamp, original.Timestamp)
+	}
+	if decoded.SourceIP != original.SourceIP {
+		t.Errorf("SourceIP: got %q, want %q", decoded.SourceIP, original.SourceIP)
+	}
+	if decoded.ThreatType != original.ThreatType {
+		t.Errorf("ThreatType: got %q, want %q", decoded.ThreatType, original.ThreatType)
+	}
+	if string(decoded.Payload) != string(original.Payload) {
+		t.Errorf("Payload: got %q, want %q", decoded.Payload, original.Payload)
+	}
+	if decoded.Severity != original.Severity {
+		t.Errorf("Severity: got %d, want %d", decoded.Severity, original.Severity)
+	}
+	if decoded.Confidence != original.Confidence {
+		t.Errorf("Confidence: got %v, want %v", decoded.Confidence, original.Confidence)
+	}
+	if decoded.Metadata["rule"] != "yara-42" {
+		t.Errorf("Metadata[rule]: got %q, want %q", decoded.Metadata["rule"], "yara-42")
+	}
+}
+
+func TestUnmarshalSkipsUnknownFields(t *testing.T) {
+	known, err := Marshal(&ThreatEvent{ThreatType: "ddos"})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	data := append(appendTagVarint(nil, 99, 42), known...)
+
+	var decoded ThreatEvent
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal should skip unknown fields, got error: %v", err)
+	}
+	if decoded.ThreatType != "ddos" {
+		t.Errorf("ThreatType: got %q, want %q", decoded.ThreatType, "ddos")
+	}
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"bufio"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// writerSink is a Sink that frames each event as a 4-byte big-endian
+// length prefix followed by its JSON encoding, and writes the frames to an
+// underlying io.Writer. It's the backing implementation for SINK_FILE_PATH;
+// see Reader for the inverse operation.
+type writerSink struct {
+	mu sync.Mutex
+	w  io.Writer
+	bw *bufio.Writer
+}
+
+// newWriterSink wraps w in a buffered frame writer. The caller still owns
+// w; Close only closes it if w also implements io.Closer.
+func newWriterSink(w io.Writer) *writerSink {
+	return &writerSink{w: w, bw: bufio.NewWriter(w)}
+}
+
+func (s *writerSink) Write(event *ThreatEvent) error {
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("marshaling event for writer sink: %w", err)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var length [4]byte
+	binary.BigEndian.PutUint32(length[:], uint32(len(payload)))
+	if _, err := s.bw.Write(length[:]); err != nil {
+		return fmt.Errorf("writing frame length: %w", err)
+	}
+	if _, err := s.bw.Write(payload); err != nil {
+		return fmt.Errorf("writing frame payload: %w", err)
+	}
+	return nil
+}
+
+func (s *writerSink) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.bw.Flush()
+}
+
+func (s *writerSink) Close() error {
+	if err := s.Flush(); err != nil {
+		return err
+	}
+	if c, ok := s.w.(io.Closer); ok {
+		return c.Close()
+	}
+	return nil
+}